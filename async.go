@@ -0,0 +1,283 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// AsyncPolicy defines what happens when an entry is logged while the async
+// buffer is full.
+type AsyncPolicy int
+
+const (
+	// AsyncBlock blocks the logging call until there is room in the buffer.
+	AsyncBlock AsyncPolicy = iota
+	// AsyncDropNewest discards the entry being logged.
+	AsyncDropNewest
+	// AsyncDropOldest discards the oldest buffered entry to make room.
+	AsyncDropOldest
+	// AsyncDropBelowLevel discards the entry being logged if it is less
+	// severe than `AsyncOptions.Level`, more severe entries block.
+	AsyncDropBelowLevel
+)
+
+// DefaultAsyncSize is the default number of entries buffered in async mode.
+const DefaultAsyncSize = 1024
+
+// AsyncOptions configures asynchronous output, see `Logger.SetAsync`.
+type AsyncOptions struct {
+	// Size is the number of entries that can be buffered. Defaults to
+	// DefaultAsyncSize.
+	Size int
+
+	// Policy defines what happens when the buffer is full.
+	Policy AsyncPolicy
+
+	// Level is the least severe level that is never dropped by the
+	// AsyncDropBelowLevel policy.
+	Level stdLogger.Level
+}
+
+// AsyncStats contains counters for asynchronous output.
+type AsyncStats struct {
+	// Written is the number of entries written to the output.
+	Written uint64
+	// Dropped is the number of entries discarded because the buffer was
+	// full.
+	Dropped uint64
+}
+
+// SetAsync enables asynchronous output. Formatted entries are queued in a
+// bounded ring buffer and written to `Out` by a background goroutine, so a
+// slow writer no longer blocks the logging goroutines. The buffer is flushed
+// by `Flush`, `Close` and when `Exit` is called, e.g. by a Fatal log call.
+//
+// Calling SetAsync again flushes and replaces the current buffer, call
+// `Close` to return to synchronous output.
+func (logger *Logger) SetAsync(opts AsyncOptions) {
+	logger = logger.root()
+	if opts.Size <= 0 {
+		opts.Size = DefaultAsyncSize
+	}
+	w := newAsyncWriter(logger, opts)
+
+	logger.mu.Lock()
+	prev := logger.asyncWriter()
+	logger.async.Store(w)
+	registerExit := !logger.asyncExit
+	logger.asyncExit = true
+	logger.mu.Unlock()
+
+	if nil != prev {
+		prev.close()
+	}
+	if registerExit {
+		RegisterExitHandler(logger.Flush)
+	}
+}
+
+// Flush blocks until all buffered entries have been written. It does
+// nothing if async output is not enabled.
+func (logger *Logger) Flush() {
+	logger = logger.root()
+	if w := logger.asyncWriter(); nil != w {
+		w.flush()
+	}
+}
+
+// Close flushes all buffered entries, stops the background writer and
+// returns the logger to synchronous output.
+func (logger *Logger) Close() error {
+	logger = logger.root()
+	logger.mu.Lock()
+	w := logger.asyncWriter()
+	logger.async.Store((*asyncWriter)(nil))
+	logger.mu.Unlock()
+
+	if nil != w {
+		w.close()
+	}
+	return nil
+}
+
+// AsyncStats returns the async output counters. All counters are zero if
+// async output is not enabled.
+func (logger *Logger) AsyncStats() AsyncStats {
+	logger = logger.root()
+	if w := logger.asyncWriter(); nil != w {
+		return w.stats()
+	}
+	return AsyncStats{}
+}
+
+func (logger *Logger) asyncWriter() *asyncWriter {
+	w, _ := logger.async.Load().(*asyncWriter)
+	return w
+}
+
+// asyncRecord is a formatted entry waiting to be written.
+type asyncRecord struct {
+	level stdLogger.Level
+	data  []byte
+}
+
+// asyncWriter is a bounded ring buffer of formatted entries drained by a
+// background goroutine.
+type asyncWriter struct {
+	// accessed atomically, keep 64-bit aligned.
+	written uint64
+	dropped uint64
+
+	logger *Logger
+	opts   AsyncOptions
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	idle     *sync.Cond
+	records  []asyncRecord
+	head     int
+	count    int
+	busy     bool
+	closed   bool
+	done     chan struct{}
+}
+
+func newAsyncWriter(logger *Logger, opts AsyncOptions) *asyncWriter {
+	w := &asyncWriter{
+		logger:  logger,
+		opts:    opts,
+		records: make([]asyncRecord, opts.Size),
+		done:    make(chan struct{}),
+	}
+	w.notEmpty = sync.NewCond(&w.mu)
+	w.notFull = sync.NewCond(&w.mu)
+	w.idle = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// enqueue adds a formatted entry to the buffer. It returns false if the
+// writer is closed and the entry must be written synchronously, in that case
+// it first waits for the buffer to be drained to keep the output in order.
+func (w *asyncWriter) enqueue(level stdLogger.Level, data []byte) bool {
+	if w.push(level, data) {
+		return true
+	}
+	<-w.done
+	return false
+}
+
+func (w *asyncWriter) push(level stdLogger.Level, data []byte) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for !w.closed && w.count == len(w.records) {
+		switch w.opts.Policy {
+		case AsyncDropNewest:
+			atomic.AddUint64(&w.dropped, 1)
+			return true
+		case AsyncDropOldest:
+			w.records[w.head] = asyncRecord{}
+			w.head = (w.head + 1) % len(w.records)
+			w.count--
+			atomic.AddUint64(&w.dropped, 1)
+		case AsyncDropBelowLevel:
+			if level > w.opts.Level {
+				atomic.AddUint64(&w.dropped, 1)
+				return true
+			}
+			w.notFull.Wait()
+		default:
+			w.notFull.Wait()
+		}
+	}
+	if w.closed {
+		return false
+	}
+
+	// The formatter may reuse its buffer, keep a copy.
+	w.records[(w.head+w.count)%len(w.records)] = asyncRecord{
+		level: level,
+		data:  append([]byte(nil), data...),
+	}
+	w.count++
+	w.notEmpty.Signal()
+	return true
+}
+
+// run drains the buffer until the writer is closed.
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for {
+		w.mu.Lock()
+		for 0 == w.count && !w.closed {
+			w.notEmpty.Wait()
+		}
+		if 0 == w.count && w.closed {
+			w.mu.Unlock()
+			return
+		}
+		record := w.records[w.head]
+		w.records[w.head] = asyncRecord{}
+		w.head = (w.head + 1) % len(w.records)
+		w.count--
+		w.busy = true
+		w.notFull.Signal()
+		w.mu.Unlock()
+
+		w.write(record)
+
+		w.mu.Lock()
+		w.busy = false
+		if 0 == w.count {
+			w.idle.Broadcast()
+		}
+		w.mu.Unlock()
+	}
+}
+
+// write writes a record without holding the logger lock, so a slow writer
+// does not block the logging goroutines.
+func (w *asyncWriter) write(record asyncRecord) {
+	w.logger.mu.Lock()
+	out := w.logger.Out
+	w.logger.mu.Unlock()
+	if _, err := out.Write(record.data); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
+		return
+	}
+	atomic.AddUint64(&w.written, 1)
+}
+
+func (w *asyncWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for (w.count > 0 || w.busy) && !w.closed {
+		w.idle.Wait()
+	}
+}
+
+// close stops accepting entries and waits for the buffer to be drained.
+func (w *asyncWriter) close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		w.notEmpty.Broadcast()
+		w.notFull.Broadcast()
+		w.idle.Broadcast()
+	}
+	w.mu.Unlock()
+	<-w.done
+}
+
+func (w *asyncWriter) stats() AsyncStats {
+	return AsyncStats{
+		Written: atomic.LoadUint64(&w.written),
+		Dropped: atomic.LoadUint64(&w.dropped),
+	}
+}
@@ -0,0 +1,194 @@
+package log
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// DefaultAsyncHookQueueSize is the default number of entries queued for an
+// `AsyncHook`.
+const DefaultAsyncHookQueueSize = 256
+
+var (
+	// ErrHookQueueFull is reported when an entry is discarded because the
+	// queue of an `AsyncHook` is full.
+	ErrHookQueueFull = errors.New("hook queue is full")
+
+	// ErrHookTimeout is reported when an `AsyncHook` does not return within
+	// its timeout.
+	ErrHookTimeout = errors.New("hook timed out")
+
+	// ErrHookBusy is reported when an entry is discarded because every call
+	// an `AsyncHook` may run is still running after timing out.
+	ErrHookBusy = errors.New("hook is busy with timed out calls")
+)
+
+// AsyncHookOptions configures an `AsyncHook`.
+type AsyncHookOptions struct {
+	// Workers is the number of goroutines firing the hook. Defaults to 1.
+	Workers int
+
+	// QueueSize is the number of entries that can be queued. Defaults to
+	// DefaultAsyncHookQueueSize.
+	QueueSize int
+
+	// Timeout is how long a worker waits for the hook to return. The
+	// entry's Context carries the deadline so the hook can give up. When it
+	// elapses ErrHookTimeout is reported and the worker moves on to the
+	// next entry while the hook keeps running. At most Workers calls run at
+	// once, entries that find them all timed out and still running are
+	// discarded and ErrHookBusy is reported. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// AsyncHook fires a hook on a bounded pool of background workers so a slow
+// hook does not block the logging call. The hook receives a copy of the
+// entry. Entries logged while the queue is full are discarded and
+// ErrHookQueueFull is returned, failures of the wrapped hook are reported to
+// the logger's `HookErrorHandler`.
+//
+//	hook := log.NewAsyncHook(slowHook, log.AsyncHookOptions{Timeout: time.Second})
+//	defer hook.Close()
+//	logger.AddHook(hook)
+type AsyncHook struct {
+	hook    Hook
+	timeout time.Duration
+	queue   chan *Entry
+	wg      sync.WaitGroup
+
+	// calls holds a token for every running call of a hook with a timeout.
+	calls chan struct{}
+
+	dropped uint64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncHook wraps a hook and starts its workers.
+func NewAsyncHook(hook Hook, opts AsyncHookOptions) *AsyncHook {
+	if opts.Workers <= 0 {
+		opts.Workers = 1
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DefaultAsyncHookQueueSize
+	}
+	h := &AsyncHook{
+		hook:    hook,
+		timeout: opts.Timeout,
+		queue:   make(chan *Entry, opts.QueueSize),
+		calls:   make(chan struct{}, opts.Workers),
+	}
+	h.wg.Add(opts.Workers)
+	for i := 0; i < opts.Workers; i++ {
+		go h.run()
+	}
+	return h
+}
+
+// Levels implements Hook.
+func (h *AsyncHook) Levels() []stdLogger.Level {
+	return h.hook.Levels()
+}
+
+// Fire implements Hook. It queues a copy of the entry, once the hook is
+// closed the wrapped hook is fired synchronously.
+func (h *AsyncHook) Fire(entry *Entry) error {
+	clone := entry.clone()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		if err := fireHook(h.hook, clone); nil != err {
+			return err
+		}
+		return nil
+	}
+	select {
+	case h.queue <- clone:
+		return nil
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+		return ErrHookQueueFull
+	}
+}
+
+// Dropped returns the number of entries discarded because the queue was full
+// or the hook was busy.
+func (h *AsyncHook) Dropped() uint64 {
+	return atomic.LoadUint64(&h.dropped)
+}
+
+// Close fires the queued entries and stops the workers. It does not wait for
+// hooks that timed out.
+func (h *AsyncHook) Close() error {
+	h.mu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.mu.Unlock()
+	h.wg.Wait()
+	return nil
+}
+
+func (h *AsyncHook) run() {
+	defer h.wg.Done()
+	for entry := range h.queue {
+		if err := h.fire(entry); nil != err {
+			entry.Logger.handleHookError(entry, err)
+		}
+	}
+}
+
+// fire fires the wrapped hook, waiting at most for the timeout.
+func (h *AsyncHook) fire(entry *Entry) *HookError {
+	if h.timeout <= 0 {
+		return fireHook(h.hook, entry)
+	}
+
+	select {
+	case h.calls <- struct{}{}:
+	default:
+		atomic.AddUint64(&h.dropped, 1)
+		return &HookError{Hook: h.hook, Err: ErrHookBusy}
+	}
+	parent := entry.Context
+	if nil == parent {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, h.timeout)
+	entry.Context = ctx
+
+	done := make(chan *HookError, 1)
+	go func() {
+		defer func() { <-h.calls }()
+		defer cancel()
+		done <- fireHook(h.hook, entry)
+	}()
+	timer := time.NewTimer(h.timeout)
+	defer timer.Stop()
+	select {
+	case err := <-done:
+		return err
+	case <-timer.C:
+		return &HookError{Hook: h.hook, Err: ErrHookTimeout}
+	}
+}
+
+// clone returns a copy of the entry that can be used after the logging call
+// returns.
+func (entry *Entry) clone() *Entry {
+	clone := *entry
+	clone.Buffer = nil
+	clone.Data = make(Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		clone.Data[k] = v
+	}
+	return &clone
+}
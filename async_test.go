@@ -0,0 +1,208 @@
+package log
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// gateWriter blocks every write until the gate is opened.
+type gateWriter struct {
+	mu   sync.Mutex
+	buf  bytes.Buffer
+	gate chan struct{}
+	once sync.Once
+	// closed when the first write is attempted.
+	started chan struct{}
+}
+
+func newGateWriter() *gateWriter {
+	return &gateWriter{
+		gate:    make(chan struct{}),
+		started: make(chan struct{}),
+	}
+}
+
+func (w *gateWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.gate
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gateWriter) lines() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Split(strings.TrimSpace(w.buf.String()), "\n")
+}
+
+func newAsyncLogger(out *gateWriter) *Logger {
+	logger := New()
+	logger.Out = out
+	logger.Formatter = &TextFormatter{DisableTimestamp: true, DisableTTY: true}
+	logger.SetLevel(DebugLevel)
+	return logger
+}
+
+func TestAsyncFlush(t *testing.T) {
+	out := newGateWriter()
+	close(out.gate)
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{})
+	defer logger.Close()
+
+	for i := 0; i < 100; i++ {
+		logger.Info("message")
+	}
+	logger.Flush()
+
+	assert.Len(t, out.lines(), 100)
+	assert.Equal(t, AsyncStats{Written: 100}, logger.AsyncStats())
+}
+
+func TestAsyncCloseReturnsToSync(t *testing.T) {
+	out := newGateWriter()
+	close(out.gate)
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{Size: 4})
+
+	logger.Info("async")
+	assert.NoError(t, logger.Close())
+	logger.Info("sync")
+
+	lines := out.lines()
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[0], "async")
+		assert.Contains(t, lines[1], "sync")
+	}
+	assert.Equal(t, AsyncStats{}, logger.AsyncStats())
+}
+
+// fillAsync blocks the background writer on the first entry and fills the
+// buffer with n more entries.
+func fillAsync(logger *Logger, out *gateWriter, n int) {
+	logger.Info("first")
+	<-out.started
+	for i := 0; i < n; i++ {
+		logger.Info("buffered")
+	}
+}
+
+func TestAsyncDropNewest(t *testing.T) {
+	out := newGateWriter()
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{Size: 2, Policy: AsyncDropNewest})
+	defer logger.Close()
+
+	fillAsync(logger, out, 2)
+	logger.Info("dropped")
+	assert.Equal(t, uint64(1), logger.AsyncStats().Dropped)
+
+	close(out.gate)
+	logger.Flush()
+	lines := out.lines()
+	assert.Len(t, lines, 3)
+	assert.NotContains(t, strings.Join(lines, "\n"), "dropped")
+}
+
+func TestAsyncDropOldest(t *testing.T) {
+	out := newGateWriter()
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{Size: 2, Policy: AsyncDropOldest})
+	defer logger.Close()
+
+	fillAsync(logger, out, 2)
+	logger.Info("newest")
+	assert.Equal(t, uint64(1), logger.AsyncStats().Dropped)
+
+	close(out.gate)
+	logger.Flush()
+	lines := out.lines()
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], "first")
+		assert.Contains(t, lines[1], "buffered")
+		assert.Contains(t, lines[2], "newest")
+	}
+}
+
+func TestAsyncDropBelowLevel(t *testing.T) {
+	out := newGateWriter()
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{Size: 2, Policy: AsyncDropBelowLevel, Level: WarnLevel})
+	defer logger.Close()
+
+	fillAsync(logger, out, 2)
+	logger.Debug("debug dropped")
+	logger.Info("info dropped")
+	assert.Equal(t, uint64(2), logger.AsyncStats().Dropped)
+
+	done := make(chan struct{})
+	go func() {
+		logger.Error("error kept")
+		close(done)
+	}()
+	close(out.gate)
+	<-done
+	logger.Flush()
+
+	all := strings.Join(out.lines(), "\n")
+	assert.NotContains(t, all, "dropped")
+	assert.Contains(t, all, "error kept")
+	assert.Equal(t, AsyncStats{Written: 4, Dropped: 2}, logger.AsyncStats())
+}
+
+func TestAsyncBlock(t *testing.T) {
+	out := newGateWriter()
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{Size: 1})
+	defer logger.Close()
+
+	fillAsync(logger, out, 1)
+	done := make(chan struct{})
+	go func() {
+		logger.Info("blocked")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("log call did not block on a full buffer")
+	default:
+	}
+
+	close(out.gate)
+	<-done
+	logger.Flush()
+	assert.Len(t, out.lines(), 3)
+	assert.Equal(t, uint64(0), logger.AsyncStats().Dropped)
+}
+
+func TestAsyncExitHandlerFlushes(t *testing.T) {
+	out := newGateWriter()
+	close(out.gate)
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{})
+	defer logger.Close()
+
+	for i := 0; i < 10; i++ {
+		logger.Info("message")
+	}
+	runHandlers()
+	assert.Len(t, out.lines(), 10)
+}
+
+func TestAsyncPanicFlushes(t *testing.T) {
+	out := newGateWriter()
+	close(out.gate)
+	logger := newAsyncLogger(out)
+	logger.SetAsync(AsyncOptions{})
+	defer logger.Close()
+
+	assert.Panics(t, func() {
+		logger.Panic("panic message")
+	})
+	assert.Contains(t, out.lines()[0], "panic message")
+}
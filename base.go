@@ -0,0 +1,83 @@
+package log
+
+// SetBaseFields replaces the logger's base fields. Base fields are added to
+// every entry logged through the logger, including entries from `Writer` and
+// the package-level functions for the standard logger, without a call to
+// `WithFields`:
+//
+//	logger.SetBaseFields(log.Fields{"service": "api", "version": version})
+//
+// Fields set on an entry take precedence over base fields with the same key.
+func (logger *Logger) SetBaseFields(fields Fields) {
+	logger.setBase(Fields{}.merge(fields))
+}
+
+// BaseFields returns a copy of the logger's base fields.
+func (logger *Logger) BaseFields() Fields {
+	return Fields{}.merge(logger.baseFields())
+}
+
+// Child returns a logger that adds fields to a copy of this logger's base
+// fields. The child writes through its parent: it shares the parent's
+// output, formatter, hooks, level and other settings, and changing them on
+// either logger changes them for both. The child's own `Out`, `Formatter`,
+// `Hooks`, `Level` and `Redactor` fields are not used.
+//
+//	db := logger.Child(log.Fields{"component": "db"})
+//	db.Info("connected") // includes the parent's base fields and "component"
+func (logger *Logger) Child(fields Fields) *Logger {
+	child := &Logger{parent: logger.root()}
+	child.setBase(logger.baseFields().merge(fields))
+	return child
+}
+
+// baseSet is the logger's base fields and their keys, shared by the entries
+// created by the logger.
+type baseSet struct {
+	fields Fields
+	keys   []fieldKey
+}
+
+func (logger *Logger) setBase(fields Fields) {
+	logger.base.Store(baseSet{fields: fields, keys: batchKeys(fields)})
+}
+
+func (logger *Logger) baseFields() Fields {
+	base, _ := logger.base.Load().(baseSet)
+	return base.fields
+}
+
+func (logger *Logger) baseKeys() []fieldKey {
+	base, _ := logger.base.Load().(baseSet)
+	return base.keys
+}
+
+// baseData returns a new data map for an entry, holding the base fields.
+func (logger *Logger) baseData() Fields {
+	if base := logger.baseFields(); 0 < len(base) {
+		return Fields{}.merge(base)
+	}
+	// Default is five fields, give a little extra room
+	return make(Fields, 5)
+}
+
+// root returns the logger that owns the output and settings, the logger
+// itself unless it was created by `Child`.
+func (logger *Logger) root() *Logger {
+	if nil != logger.parent {
+		return logger.parent
+	}
+	return logger
+}
+
+// merge returns a copy of fields with more added.
+func (fields Fields) merge(more Fields) Fields {
+	data := make(Fields, len(fields)+len(more))
+	for k, v := range fields {
+		data[k] = v
+	}
+	for k, v := range more {
+		data[k] = v
+	}
+	return data
+}
@@ -0,0 +1,173 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaseFields(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.SetBaseFields(Fields{"service": "api", "env": "prod"})
+		log.WithField("env", "dev").Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "api", data.Data["service"])
+		assert.Equal(t, "dev", data.Data["env"])
+	})
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.SetBaseFields(Fields{"service": "api"})
+		log.Infof("test %d", 1)
+	}, func(data logData) {
+		assert.Equal(t, "api", data.Data["service"])
+	})
+}
+
+func TestBaseFieldsAreCopied(t *testing.T) {
+	logger := New()
+	fields := Fields{"service": "api"}
+	logger.SetBaseFields(fields)
+	fields["service"] = "changed"
+	assert.Equal(t, Fields{"service": "api"}, logger.BaseFields())
+
+	logger.BaseFields()["service"] = "changed"
+	assert.Equal(t, Fields{"service": "api"}, logger.BaseFields())
+}
+
+func TestBaseFieldsCleared(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTTY: true}
+
+	// Pooled entries must not keep fields that were removed.
+	logger.SetBaseFields(Fields{"service": "api"})
+	logger.Info("first")
+	logger.SetBaseFields(nil)
+	buffer.Reset()
+	logger.Info("second")
+	assert.NotContains(t, buffer.String(), "service")
+}
+
+func TestBaseFieldsWriter(t *testing.T) {
+	logger := New()
+	// Entries are not built at all for ioutil.Discard.
+	logger.Out = &bytes.Buffer{}
+	logger.SetBaseFields(Fields{"service": "api"})
+	hook := &chanHook{entries: make(chan Entry, 1)}
+	logger.AddHook(hook)
+
+	w := logger.Writer()
+	defer w.Close()
+	_, err := w.Write([]byte("from writer\n"))
+	assert.Nil(t, err)
+
+	select {
+	case entry := <-hook.entries:
+		assert.Equal(t, "from writer", entry.Message)
+		assert.Equal(t, "api", entry.Data["service"])
+	case <-time.After(5 * time.Second):
+		t.Fatal("entry was not logged")
+	}
+}
+
+type chanHook struct {
+	entries chan Entry
+}
+
+func (hook *chanHook) Fire(entry *Entry) error {
+	hook.entries <- *entry
+	return nil
+}
+
+func (hook *chanHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestBaseFieldsStandardLogger(t *testing.T) {
+	defer newStd()
+	var buffer bytes.Buffer
+	SetOutput(&buffer)
+	SetFormatter(&TextFormatter{DisableTTY: true})
+	SetBaseFields(Fields{"version": "1.2.3"})
+
+	Info("test")
+	assert.Contains(t, buffer.String(), `version="1.2.3"`)
+}
+
+func TestChildLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTTY: true}
+	logger.SetBaseFields(Fields{"service": "api"})
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+
+	child := logger.Child(Fields{"module": "db"})
+	grandchild := child.Child(Fields{"table": "users"})
+	grandchild.WithField("id", 1).Warn("test")
+
+	out := buffer.String()
+	assert.Contains(t, out, `data.service="api"`)
+	assert.Contains(t, out, `data.module="db"`)
+	assert.Contains(t, out, `data.table="users"`)
+	assert.Contains(t, out, `data.id=1`)
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, logger, hook.Entries[0].Logger)
+	}
+
+	// The parent is not affected.
+	buffer.Reset()
+	logger.Info("parent")
+	assert.NotContains(t, buffer.String(), "module")
+}
+
+func TestChildLoggerSharesSettings(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = ioutil.Discard
+	child := logger.Child(Fields{"module": "db"})
+
+	child.SetOutput(&buffer)
+	assert.Equal(t, &buffer, logger.Out)
+
+	child.SetLevel(WarnLevel)
+	assert.Equal(t, WarnLevel, logger.GetLevel())
+	child.Info("dropped")
+	assert.Empty(t, buffer.String())
+
+	logger.SetLevel(DebugLevel)
+	assert.Equal(t, DebugLevel, child.GetLevel())
+	child.Debug("kept")
+	assert.Contains(t, buffer.String(), "kept")
+
+	hook := &RecordHook{}
+	child.AddHook(hook)
+	logger.Info("test")
+	assert.Len(t, hook.Entries, 1)
+}
+
+func TestChildLoggerConcurrent(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddHook(&ModifyHook{})
+	child := logger.Child(Fields{"module": "db"})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				child.Info("test")
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,224 @@
+package log
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// CallerFormatter is an optional interface implemented by formatters that
+// know whether they render the caller and the stack trace. The caller is
+// captured at the log site only when the formatter needs it, formatters that
+// do not implement the interface are always given the caller but never the
+// trace.
+type CallerFormatter interface {
+	NeedsCaller() (caller, trace bool)
+}
+
+// maxCallerDepth is the maximum number of stack frames captured for an
+// entry.
+const maxCallerDepth = 64
+
+// SetCallerLevel will adjust the relative caller level in log output.
+func SetCallerLevel(level int) {
+	callerLevel = level
+}
+
+var callerLevel int
+
+// pcPool holds buffers for runtime.Callers.
+var pcPool = sync.Pool{
+	New: func() interface{} {
+		return new([maxCallerDepth]uintptr)
+	},
+}
+
+// frameCache maps program counters to their resolved, possibly inlined,
+// frames.
+var frameCache sync.Map
+
+// framesForPC returns the frames for a return program counter, innermost
+// first. The result is shared and must not be modified.
+func framesForPC(pc uintptr) []runtime.Frame {
+	if cached, ok := frameCache.Load(pc); ok {
+		return cached.([]runtime.Frame)
+	}
+	frames := []runtime.Frame{}
+	iter := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := iter.Next()
+		frames = append(frames, frame)
+		if !more {
+			break
+		}
+	}
+	frameCache.Store(pc, frames)
+	return frames
+}
+
+// logPackage is the prefix of the names of this package's functions, its
+// import path followed by a dot. Subpackages such as http and grpc don't
+// have it, entries they log report their own frames as the caller.
+var logPackage = packagePrefix(runtime.FuncForPC(reflect.ValueOf(formatFrame).Pointer()).Name())
+
+// packagePrefix returns the package path of a function name followed by a
+// dot, e.g. "github.com/bdlm/log/v2." for
+// "github.com/bdlm/log/v2.(*Entry).Info".
+func packagePrefix(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); 0 <= dot {
+		return function[:slash+1+dot+1]
+	}
+	return function
+}
+
+// isLogFrame reports whether a frame belongs to this package and must be
+// skipped when looking for the caller. Test files are never skipped.
+func isLogFrame(frame *runtime.Frame) bool {
+	return strings.HasPrefix(frame.Function, logPackage) &&
+		!strings.HasSuffix(frame.File, "_test.go")
+}
+
+// stack is a captured call stack, the first frame is the innermost one.
+type stack []*runtime.Frame
+
+// captureStack returns the current call stack, skipping skip frames in
+// addition to captureStack itself.
+func captureStack(skip int) stack {
+	pcs := pcPool.Get().(*[maxCallerDepth]uintptr)
+	defer pcPool.Put(pcs)
+
+	n := runtime.Callers(skip+2, pcs[:])
+	frames := make(stack, 0, n)
+	for _, pc := range pcs[:n] {
+		resolved := framesForPC(pc)
+		for i := range resolved {
+			frames = append(frames, &resolved[i])
+		}
+	}
+	return frames
+}
+
+// callerIndex returns the index of the first frame outside this package, or
+// -1.
+func (s stack) callerIndex() int {
+	for i, frame := range s {
+		if !isLogFrame(frame) {
+			return i
+		}
+	}
+	return -1
+}
+
+// offset applies the `SetCallerLevel` offset to the frame at index i.
+func (s stack) offset(i int) *runtime.Frame {
+	if j := i + callerLevel; 0 != callerLevel && j >= 0 && j < len(s) {
+		return s[j]
+	}
+	return s[i]
+}
+
+// caller returns the calling frame.
+func (s stack) caller() *runtime.Frame {
+	i := s.callerIndex()
+	if i < 0 {
+		return nil
+	}
+	return s.offset(i)
+}
+
+// trace returns the formatted frames outside this package, excluding the two
+// outermost frames, which belong to the runtime.
+func (s stack) trace() []string {
+	trace := []string{}
+	for i, frame := range s {
+		if !isLogFrame(frame) {
+			trace = append(trace, formatFrame(s.offset(i)))
+		}
+	}
+	if len(trace) > 2 {
+		trace = trace[:len(trace)-2]
+	}
+	return trace
+}
+
+func formatFrame(frame *runtime.Frame) string {
+	if nil == frame {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d %s", path.Base(frame.File), frame.Line, frame.Function)
+}
+
+// callerNeeds returns whether the logger's formatter needs the caller and
+// the stack trace.
+func (logger *Logger) callerNeeds() (caller, trace bool) {
+	if f, ok := logger.Formatter.(CallerFormatter); ok {
+		return f.NeedsCaller()
+	}
+	return true, false
+}
+
+// findCaller returns the first frame outside this package without building
+// the whole stack, skipping skip frames in addition to findCaller itself.
+func findCaller(skip int) *runtime.Frame {
+	pcs := pcPool.Get().(*[maxCallerDepth]uintptr)
+	defer pcPool.Put(pcs)
+
+	n := runtime.Callers(skip+2, pcs[:])
+	for _, pc := range pcs[:n] {
+		resolved := framesForPC(pc)
+		for i := range resolved {
+			if !isLogFrame(&resolved[i]) {
+				return &resolved[i]
+			}
+		}
+	}
+	return nil
+}
+
+// captureCaller records the caller, and the stack trace if needed, at the
+// log site. A caller or trace set on the entry before it is logged, e.g. by
+// an adapter that knows the real log site, is kept.
+func (entry *Entry) captureCaller() {
+	caller, trace := entry.Logger.callerNeeds()
+	entry.callerCaptured = true
+	switch {
+	case trace && (nil == entry.Caller || nil == entry.Stack):
+		s := captureStack(1)
+		if nil == entry.Caller {
+			entry.Caller = s.caller()
+		}
+		if nil == entry.Stack {
+			entry.Stack = s.trace()
+		}
+	case nil != entry.Caller, !caller:
+	case 0 == callerLevel:
+		entry.Caller = findCaller(1)
+	default:
+		entry.Caller = captureStack(1).caller()
+	}
+}
+
+// callerString returns the formatted caller. Entries that were not captured
+// at a log site and have no caller set, e.g. when a formatter is called
+// directly, resolve the caller from the current stack.
+func (entry *Entry) callerString() string {
+	if entry.callerCaptured || nil != entry.Caller {
+		return formatFrame(entry.Caller)
+	}
+	return formatFrame(captureStack(1).caller())
+}
+
+// stackTrace returns the formatted stack trace, see callerString.
+func (entry *Entry) stackTrace() []string {
+	if entry.callerCaptured || nil != entry.Stack {
+		if nil == entry.Stack {
+			return []string{}
+		}
+		return entry.Stack
+	}
+	return captureStack(1).trace()
+}
@@ -0,0 +1,137 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallerCapturedAtLogSite(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithField("k", "v").Info("test")
+	}, func(data logData) {
+		assert.True(t, strings.HasPrefix(data.Caller, "caller_test.go:"), data.Caller)
+		assert.True(t, strings.HasSuffix(data.Caller, "TestCallerCapturedAtLogSite.func1"), data.Caller)
+	})
+}
+
+func TestCallerExposedToHooks(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+
+	logger.Info("test")
+
+	if assert.Len(t, hook.Entries, 1) && assert.NotNil(t, hook.Entries[0].Caller) {
+		assert.True(t, strings.HasSuffix(hook.Entries[0].Caller.File, "caller_test.go"))
+		assert.True(t, strings.HasSuffix(hook.Entries[0].Caller.Function, "TestCallerExposedToHooks"))
+	}
+}
+
+func TestCallerNotCapturedWhenDisabled(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.Formatter = &JSONFormatter{DisableCaller: true}
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+
+	logger.Info("test")
+
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Nil(t, hook.Entries[0].Caller)
+	}
+	assert.NotContains(t, logger.Out.(*bytes.Buffer).String(), "caller_test.go")
+}
+
+func TestTraceCapturedAtLogSite(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{EnableTrace: true}
+
+	logger.Info("test")
+
+	data := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &data))
+	trace, _ := data["trace"].([]interface{})
+	if assert.NotEmpty(t, trace) {
+		assert.True(t, strings.HasPrefix(trace[0].(string), "caller_test.go:"), trace[0])
+	}
+	for _, frame := range trace {
+		assert.NotContains(t, frame, "getEntryData")
+	}
+}
+
+func TestCallerFormatterCalledDirectly(t *testing.T) {
+	// Entries that were not logged resolve the caller when formatted.
+	entry := NewEntry(New())
+	serialized, err := (&TextFormatter{DisableTTY: true}).Format(entry)
+	assert.Nil(t, err)
+	assert.Contains(t, string(serialized), "caller_test.go:")
+}
+
+func TestPresetCallerTraceAndHost(t *testing.T) {
+	frame := &runtime.Frame{File: "/src/main.go", Line: 12, Function: "main.main"}
+	trace := []string{"main.go:12 main.main"}
+
+	// Formatted directly.
+	entry := NewEntry(New())
+	entry.Caller = frame
+	entry.Stack = trace
+	entry.Host = "remote"
+	serialized, err := (&JSONFormatter{DisableTTY: true, EnableTrace: true}).Format(entry)
+	assert.Nil(t, err)
+	data := logData{}
+	assert.Nil(t, json.Unmarshal(serialized, &data))
+	assert.Equal(t, "main.go:12 main.main", data.Caller)
+	assert.Equal(t, trace, data.Trace)
+	assert.Equal(t, "remote", data.Hostname)
+
+	// Logged.
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{EnableTrace: true}
+	entry = logger.WithField("k", "v")
+	entry.Caller = frame
+	entry.Stack = trace
+	entry.Info("test")
+	data = logData{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &data))
+	assert.Equal(t, "main.go:12 main.main", data.Caller)
+	assert.Equal(t, trace, data.Trace)
+}
+
+func TestIsLogFrame(t *testing.T) {
+	assert.Equal(t, "github.com/bdlm/log/v2.", logPackage)
+	assert.Equal(t, "example.com/a.b/pkg.", packagePrefix("example.com/a.b/pkg.(*T).M.func1"))
+
+	for function, skipped := range map[string]bool{
+		"github.com/bdlm/log/v2.(*Entry).Info":        true,
+		"github.com/bdlm/log/v2.(*Logger).Log.func1":  true,
+		"github.com/bdlm/log/v2/http.Handler.func1":   false,
+		"github.com/bdlm/log/v2/grpc.(*clientStream)": false,
+		"github.com/bdlm/logger.Info":                 false,
+		"main.main":                                   false,
+	} {
+		assert.Equal(t, skipped, isLogFrame(&runtime.Frame{Function: function, File: "/src/x.go"}), function)
+	}
+	assert.False(t, isLogFrame(&runtime.Frame{Function: "github.com/bdlm/log/v2.TestX", File: "/src/x_test.go"}))
+}
+
+func BenchmarkCallerCapture(b *testing.B) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Out.(*bytes.Buffer).Reset()
+		logger.Info("test")
+	}
+}
@@ -0,0 +1,315 @@
+/*
+Command logpretty renders JSON log lines with the colored TTY formatters, for
+reading machine output from production services during development.
+
+	kubectl logs -f pod/api | logpretty -level info -fields user,request_id
+
+Lines are read from the files named on the command line, or from stdin if
+there are none or a file is named "-". Lines that are not JSON log output,
+including JSON without a level or timestamp, are written unchanged and are
+not filtered by level.
+
+Usage:
+
+	logpretty [flags] [file ...]
+
+Flags:
+
+	-level level
+		show entries at this level or more severe, all if not set
+	-fields list
+		comma separated data fields to show, all if empty
+	-fieldmap list
+		comma separated label=key pairs naming the input's default
+		fields, e.g. "msg=message,time=@timestamp"
+	-time-format layout
+		the input's timestamp layout, RFC3339 with milliseconds if empty
+	-format text|json
+		render with the TTY text or JSON layout
+	-theme dark|light|high-contrast
+		the color theme, colors are disabled if NO_COLOR is set
+	-width columns
+		the line width text output is wrapped at, the terminal's width if 0
+	-compact
+		truncate long data values
+	-caller
+		show the caller (default true)
+	-host
+		show the hostname (default true)
+	-trace
+		show the stack trace
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/bdlm/log/v2"
+	"github.com/bdlm/log/v2/reader"
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// config holds the parsed command line.
+type config struct {
+	caller, host, trace bool
+	compact             bool
+	fields              map[string]bool
+	format              string
+	formatters          map[[2]bool]log.Formatter
+	filter              bool
+	level               stdLogger.Level
+	opts                reader.Options
+	profile             log.ColorProfile
+	theme               *log.Theme
+	width               int
+}
+
+// formatter returns the formatter for a record. Callers and hostnames the
+// record doesn't have are disabled, otherwise the formatter would fill them
+// in from this process.
+func (cfg *config) formatter(record *reader.Record) log.Formatter {
+	caller := cfg.caller && "" != record.Caller
+	host := cfg.host && "" != record.Host
+	key := [2]bool{caller, host}
+	if formatter, ok := cfg.formatters[key]; ok {
+		return formatter
+	}
+	var formatter log.Formatter
+	if "json" == cfg.format {
+		formatter = &log.JSONFormatter{
+			ForceTTY:        true,
+			DisableCaller:   !caller,
+			DisableHostname: !host,
+			EnableTrace:     cfg.trace,
+			Theme:           cfg.theme,
+			ColorProfile:    cfg.profile,
+		}
+	} else {
+		formatter = &log.TextFormatter{
+			ForceTTY:        true,
+			DisableCaller:   !caller,
+			DisableHostname: !host,
+			EnableTrace:     cfg.trace,
+			Theme:           cfg.theme,
+			ColorProfile:    cfg.profile,
+			Width:           cfg.width,
+			Compact:         cfg.compact,
+		}
+	}
+	cfg.formatters[key] = formatter
+	return formatter
+}
+
+// run runs the command and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("logpretty", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	level := flags.String("level", "", "show entries at this `level` or more severe, all if not set")
+	fields := flags.String("fields", "", "comma separated data fields to show, all if empty")
+	fieldMap := flags.String("fieldmap", "", "comma separated label=key pairs naming the input's default fields")
+	timeFormat := flags.String("time-format", "", "the input's timestamp `layout`")
+	format := flags.String("format", "text", "render with the TTY `text or json` layout")
+	theme := flags.String("theme", "dark", "the color `theme`, dark, light or high-contrast")
+	caller := flags.Bool("caller", true, "show the caller")
+	host := flags.Bool("host", true, "show the hostname")
+	trace := flags.Bool("trace", false, "show the stack trace")
+	width := flags.Int("width", 0, "the line width text output is wrapped at, the terminal's width if 0")
+	compact := flags.Bool("compact", false, "truncate long data values")
+	if err := flags.Parse(args); nil != err {
+		return 2
+	}
+
+	cfg := &config{
+		opts: reader.Options{
+			Format:          reader.FormatJSON,
+			TimestampFormat: *timeFormat,
+		},
+	}
+	var err error
+	if "" != *level {
+		if cfg.level, err = log.ParseLevel(*level); nil != err {
+			fmt.Fprintf(stderr, "logpretty: %v\n", err)
+			return 2
+		}
+		cfg.filter = true
+	}
+	if "" != *fields {
+		cfg.fields = map[string]bool{}
+		for _, field := range strings.Split(*fields, ",") {
+			cfg.fields[strings.TrimSpace(field)] = true
+		}
+	}
+	if cfg.opts.FieldMap, err = parseFieldMap(*fieldMap); nil != err {
+		fmt.Fprintf(stderr, "logpretty: %v\n", err)
+		return 2
+	}
+	switch *format {
+	case "text", "json":
+		cfg.format = *format
+	default:
+		fmt.Fprintf(stderr, "logpretty: unknown format %q\n", *format)
+		return 2
+	}
+	switch *theme {
+	case "dark":
+		cfg.theme = log.DarkTheme()
+	case "light":
+		cfg.theme = log.LightTheme()
+	case "high-contrast":
+		cfg.theme = log.HighContrastTheme()
+	default:
+		fmt.Fprintf(stderr, "logpretty: unknown theme %q\n", *theme)
+		return 2
+	}
+	// The formatters write to a buffer, detect the colors and width of a
+	// terminal here.
+	cfg.width = *width
+	if f, ok := stdout.(*os.File); ok && terminal.IsTerminal(int(f.Fd())) {
+		cfg.profile = log.DetectColorProfile()
+		if 0 == cfg.width {
+			cfg.width, _, _ = terminal.GetSize(int(f.Fd()))
+		}
+	}
+	cfg.caller, cfg.host, cfg.trace, cfg.compact = *caller, *host, *trace, *compact
+	cfg.formatters = map[[2]bool]log.Formatter{}
+
+	files := flags.Args()
+	if 0 == len(files) {
+		files = []string{"-"}
+	}
+	out := bufio.NewWriter(stdout)
+	defer out.Flush()
+	code := 0
+	for _, name := range files {
+		if err := cfg.renderFile(out, name, stdin); nil != err {
+			fmt.Fprintf(stderr, "logpretty: %v\n", err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// parseFieldMap parses label=key pairs.
+func parseFieldMap(s string) (log.FieldMap, error) {
+	fieldMap := log.FieldMap{}
+	if "" == s {
+		return fieldMap, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if 2 != len(kv) {
+			return nil, fmt.Errorf("invalid fieldmap pair %q", pair)
+		}
+		label := strings.TrimSpace(kv[0])
+		switch label {
+		case log.LabelCaller, log.LabelData, log.LabelError, log.LabelHost,
+			log.LabelLevel, log.LabelMsg, log.LabelTime, log.LabelTrace:
+			fieldMap[log.FieldLabel(label)] = strings.TrimSpace(kv[1])
+		default:
+			return nil, fmt.Errorf("unknown fieldmap label %q", label)
+		}
+	}
+	return fieldMap, nil
+}
+
+// renderFile renders the named file, "-" is stdin.
+func (cfg *config) renderFile(out *bufio.Writer, name string, stdin io.Reader) error {
+	in := stdin
+	if "-" != name {
+		f, err := os.Open(name)
+		if nil != err {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	return cfg.render(out, in)
+}
+
+// render renders every line read from in.
+func (cfg *config) render(out *bufio.Writer, in io.Reader) error {
+	logger := log.New()
+	logger.Out = out
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), reader.MaxLineSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		record, err := reader.Parse(line, cfg.opts)
+		if nil != err {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		if cfg.filter && record.HasLevel && record.Level > cfg.level {
+			continue
+		}
+		// JSON that isn't log output, or whose default fields are named
+		// differently than -fieldmap says, is written unchanged instead of
+		// being rendered as a fatal entry without a timestamp.
+		if !record.HasLevel || record.Time.IsZero() {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		byts, err := cfg.formatter(record).Format(cfg.entry(logger, record))
+		if nil != err {
+			out.Write(line)
+			out.WriteByte('\n')
+			continue
+		}
+		out.Write(byts)
+	}
+	return scanner.Err()
+}
+
+// entry returns an entry holding a decoded record.
+func (cfg *config) entry(logger *log.Logger, record *reader.Record) *log.Entry {
+	entry := log.NewEntry(logger)
+	for k, v := range record.Data {
+		if nil == cfg.fields || cfg.fields[k] {
+			entry.Data[k] = v
+		}
+	}
+	entry.Err = record.Err
+	entry.Level = record.Level
+	entry.Message = record.Message
+	entry.Time = record.Time
+	if "" != record.Caller {
+		entry.Caller = parseCaller(record.Caller)
+	}
+	entry.Stack = record.Trace
+	if nil == entry.Stack {
+		entry.Stack = []string{}
+	}
+	entry.Host = record.Host
+	return entry
+}
+
+// parseCaller parses a caller written as "file:line function".
+func parseCaller(caller string) *runtime.Frame {
+	frame := &runtime.Frame{}
+	location := caller
+	if i := strings.IndexByte(caller, ' '); 0 <= i {
+		location, frame.Function = caller[:i], caller[i+1:]
+	}
+	frame.File = location
+	if i := strings.LastIndexByte(location, ':'); 0 <= i {
+		if line, err := strconv.Atoi(location[i+1:]); nil == err {
+			frame.File, frame.Line = location[:i], line
+		}
+	}
+	return frame
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const input = `{"caller":"main.go:36 main.main","data":{"animal":"bird","count":1},"host":"myhost","level":"debug","msg":"Oh, look, a bird...","time":"2018-08-17T18:32:30.786-06:00"}
+panic: not a log line
+{"data":{"animal":"walrus"},"error":"boom","level":"error","msg":"The walrus are attacking!","time":"2018-08-17T18:32:30.786-06:00","trace":["main.go:50 main.main","proc.go:250 runtime.main"]}
+
+{"@message":"custom keys","@level":"warn","time":"2018-08-17T18:32:30.786-06:00"}
+{"user":"bob"}
+{"level":"trace","msg":"tracing along","time":"2018-08-17T18:32:30.786-06:00"}
+`
+
+func render(t *testing.T, args ...string) string {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(input), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	return stdout.String()
+}
+
+func TestRender(t *testing.T) {
+	out := render(t)
+	lines := strings.Split(out, "\n")
+	assert.Contains(t, lines[0], "debug")
+	assert.Contains(t, lines[0], "myhost")
+	assert.Contains(t, lines[0], "Oh, look, a bird...")
+	assert.Contains(t, out, "\033[")
+	assert.Contains(t, out, `"bird"`)
+	assert.Contains(t, out, "main.go:36 main.main")
+	assert.Contains(t, out, "2018-08-17T18:32:30.786-06:00")
+	assert.Contains(t, out, "\npanic: not a log line\n")
+	assert.Contains(t, out, "\n\n")
+	assert.Contains(t, out, "The walrus are attacking!")
+	assert.Contains(t, out, "boom")
+	assert.Contains(t, out, "tracing along")
+	assert.NotContains(t, out, "proc.go")
+	assert.NotContains(t, out, "logpretty")
+}
+
+func TestRenderFlags(t *testing.T) {
+	out := render(t, "-level", "error", "-caller=false", "-host=false")
+	assert.NotContains(t, out, "bird")
+	assert.NotContains(t, out, "myhost")
+	assert.NotContains(t, out, "main.go:36")
+	assert.Contains(t, out, "walrus")
+	assert.NotContains(t, out, "tracing along")
+	assert.Contains(t, out, "panic: not a log line")
+	assert.Contains(t, out, "\n{\"user\":\"bob\"}\n")
+	assert.Contains(t, out, "\n{\"@message\":\"custom keys\",")
+	assert.NotContains(t, out, "fatal")
+
+	out = render(t, "-fields", "count")
+	assert.Contains(t, out, "count")
+	assert.NotContains(t, out, "animal")
+
+	out = render(t, "-trace")
+	assert.Contains(t, out, "main.go:50 main.main")
+	assert.Contains(t, out, "proc.go:250 runtime.main")
+
+	out = render(t, "-format", "json")
+	assert.Contains(t, out, "{\n")
+	assert.Contains(t, out, "Oh, look, a bird...")
+
+	out = render(t, "-theme", "high-contrast")
+	assert.Contains(t, out, "\033[38;5;9merror")
+	assert.NotContains(t, out, "\033[38;5;166m")
+
+	out = render(t, "-width", "30", "-compact", "-host=false", "-caller=false")
+	assert.Contains(t, out, "The walrus are\n      attacking!")
+
+	out = render(t, "-fieldmap", "msg=@message,level=@level")
+	assert.Contains(t, out, "custom keys")
+	assert.Contains(t, out, "warn")
+}
+
+func TestRenderErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-level", "loud"},
+		{"-format", "yaml"},
+		{"-theme", "neon"},
+		{"-fieldmap", "message"},
+		{"-fieldmap", "body=message"},
+		{"-unknown"},
+	} {
+		var stdout, stderr bytes.Buffer
+		assert.Equal(t, 2, run(args, strings.NewReader(input), &stdout, &stderr), args)
+		assert.NotEmpty(t, stderr.String(), args)
+	}
+
+	var stdout, stderr bytes.Buffer
+	assert.Equal(t, 1, run([]string{"does-not-exist.json"}, strings.NewReader(""), &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "does-not-exist.json")
+}
+
+func TestParseCaller(t *testing.T) {
+	frame := parseCaller("main.go:36 main.main")
+	assert.Equal(t, "main.go", frame.File)
+	assert.Equal(t, 36, frame.Line)
+	assert.Equal(t, "main.main", frame.Function)
+
+	frame = parseCaller("somewhere")
+	assert.Equal(t, "somewhere", frame.File)
+	assert.Equal(t, 0, frame.Line)
+}
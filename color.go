@@ -0,0 +1,245 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorProfile is the set of colors a terminal can display.
+type ColorProfile int
+
+// Color profiles, from no color to 24-bit color.
+const (
+	// ColorsAuto detects the profile from the environment, see
+	// `DetectColorProfile`.
+	ColorsAuto ColorProfile = iota
+	// ColorsNone disables colors.
+	ColorsNone
+	// Colors16 uses the 16 ANSI colors.
+	Colors16
+	// Colors256 uses the 256 color xterm palette.
+	Colors256
+	// ColorsTrue uses 24-bit color.
+	ColorsTrue
+)
+
+// DetectColorProfile returns the color profile of the terminal described by
+// the environment. Colors are disabled if `NO_COLOR` is set or `TERM` is
+// "dumb", `COLORTERM` or `TERM` select 24-bit, 256 or 16 colors. If `TERM` is
+// not set, 256 colors are used.
+func DetectColorProfile() ColorProfile {
+	if "" != os.Getenv("NO_COLOR") {
+		return ColorsNone
+	}
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorsTrue
+	}
+	term := strings.ToLower(os.Getenv("TERM"))
+	switch {
+	case "" == term:
+		return Colors256
+	case "dumb" == term:
+		return ColorsNone
+	case strings.Contains(term, "truecolor"), strings.Contains(term, "24bit"),
+		strings.Contains(term, "direct"):
+		return ColorsTrue
+	case strings.Contains(term, "256color"):
+		return Colors256
+	}
+	return Colors16
+}
+
+// outputColorProfile returns the color profile of the formatter output.
+// `TERM` only describes terminal output, output forced to TTY format that
+// isn't a terminal uses 256 colors unless `NO_COLOR` is set.
+func outputColorProfile(isTerminal bool) ColorProfile {
+	if isTerminal || "" != os.Getenv("NO_COLOR") {
+		return DetectColorProfile()
+	}
+	return Colors256
+}
+
+// forceColor reports whether `CLICOLOR_FORCE` requests colored output even if
+// the output is not a terminal.
+func forceColor() bool {
+	force := os.Getenv("CLICOLOR_FORCE")
+	return "" != force && "0" != force && "" == os.Getenv("NO_COLOR")
+}
+
+type colorKind uint8
+
+const (
+	colorUnset colorKind = iota
+	colorRGB
+	colorIndex
+	colorEscape
+)
+
+// Color is a TTY foreground color, rendered for the terminal's color
+// profile. The zero value is no color.
+type Color struct {
+	kind    colorKind
+	r, g, b uint8
+	index   uint8
+	escape  string
+}
+
+// RGB returns a 24-bit color. Terminals without 24-bit color support show
+// the nearest palette color.
+func RGB(r, g, b uint8) Color {
+	return Color{kind: colorRGB, r: r, g: g, b: b}
+}
+
+// Hex returns a 24-bit color from its 0xRRGGBB value.
+func Hex(hex uint32) Color {
+	return RGB(uint8(hex>>16), uint8(hex>>8), uint8(hex))
+}
+
+// ANSI256 returns a color of the 256 color xterm palette. Indexes 0 to 15
+// are the 16 ANSI colors and are shown as is on 16 color terminals.
+func ANSI256(index uint8) Color {
+	return Color{kind: colorIndex, index: index}
+}
+
+// Escape returns a color written as the escape sequence seq on any color
+// terminal.
+func Escape(seq string) Color {
+	return Color{kind: colorEscape, escape: seq}
+}
+
+// IsZero reports whether c is no color.
+func (c Color) IsZero() bool {
+	return colorUnset == c.kind
+}
+
+// Sequence returns the escape sequence selecting c on a terminal with the
+// profile. It is empty if c is no color or the profile has no colors,
+// `ColorsAuto` is treated as `Colors256`.
+func (c Color) Sequence(profile ColorProfile) string {
+	if ColorsNone == profile {
+		return ""
+	}
+	switch c.kind {
+	case colorEscape:
+		return c.escape
+	case colorIndex:
+		if Colors16 == profile {
+			if c.index < 16 {
+				return ansi16Sequence(c.index)
+			}
+			r, g, b := paletteRGB(c.index)
+			return ansi16Sequence(nearestANSI16(r, g, b))
+		}
+		return fmt.Sprintf("\033[38;5;%dm", c.index)
+	case colorRGB:
+		switch profile {
+		case Colors16:
+			return ansi16Sequence(nearestANSI16(c.r, c.g, c.b))
+		case ColorsTrue:
+			return fmt.Sprintf("\033[38;2;%d;%d;%dm", c.r, c.g, c.b)
+		}
+		return fmt.Sprintf("\033[38;5;%dm", nearestANSI256(c.r, c.g, c.b))
+	}
+	return ""
+}
+
+// parseColor returns the color set by an escape sequence. 256 color palette
+// sequences, as used by the color variables, are recognized so they can be
+// shown on 16 color terminals.
+func parseColor(seq string) Color {
+	if "" == seq {
+		return Color{}
+	}
+	if strings.HasPrefix(seq, "\033[38;5;") && strings.HasSuffix(seq, "m") {
+		index, err := strconv.Atoi(seq[len("\033[38;5;") : len(seq)-1])
+		if nil == err && 0 <= index && index < 256 {
+			return ANSI256(uint8(index))
+		}
+	}
+	return Escape(seq)
+}
+
+// ansi16 holds the xterm values of the 16 ANSI colors.
+var ansi16 = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// cubeLevels holds the channel values of the 6x6x6 palette color cube.
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func ansi16Sequence(index uint8) string {
+	if index < 8 {
+		return "\033[" + strconv.Itoa(30+int(index)) + "m"
+	}
+	return "\033[" + strconv.Itoa(90+int(index)-8) + "m"
+}
+
+// paletteRGB returns the value of a 256 color palette index.
+func paletteRGB(index uint8) (r, g, b uint8) {
+	switch {
+	case index < 16:
+		c := ansi16[index]
+		return c[0], c[1], c[2]
+	case index < 232:
+		i := index - 16
+		return cubeLevels[i/36], cubeLevels[i/6%6], cubeLevels[i%6]
+	}
+	gray := 8 + 10*(index-232)
+	return gray, gray, gray
+}
+
+// nearestANSI16 returns the ANSI color closest to a 24-bit color.
+func nearestANSI16(r, g, b uint8) uint8 {
+	var nearest uint8
+	best := -1
+	for i, c := range ansi16 {
+		if d := colorDistance(r, g, b, c[0], c[1], c[2]); -1 == best || d < best {
+			nearest, best = uint8(i), d
+		}
+	}
+	return nearest
+}
+
+// nearestANSI256 returns the palette color closest to a 24-bit color, from
+// the color cube or the grayscale ramp.
+func nearestANSI256(r, g, b uint8) uint8 {
+	cube := func(v uint8) uint8 {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	cr, cg, cb := cube(r), cube(g), cube(b)
+	cubeIndex := 16 + 36*cr + 6*cg + cb
+
+	avg := (int(r) + int(g) + int(b)) / 3
+	grayStep := 23
+	if avg < 238 {
+		grayStep = (avg - 3) / 10
+		if grayStep < 0 {
+			grayStep = 0
+		}
+	}
+	grayIndex := uint8(232 + grayStep)
+	gray := uint8(8 + 10*grayStep)
+
+	if colorDistance(r, g, b, gray, gray, gray) <
+		colorDistance(r, g, b, cubeLevels[cr], cubeLevels[cg], cubeLevels[cb]) {
+		return grayIndex
+	}
+	return cubeIndex
+}
+
+func colorDistance(r1, g1, b1, r2, g2, b2 uint8) int {
+	dr, dg, db := int(r1)-int(r2), int(g1)-int(g2), int(b1)-int(b2)
+	return dr*dr + dg*dg + db*db
+}
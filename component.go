@@ -0,0 +1,114 @@
+package log
+
+import (
+	"strings"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// ComponentField is the data field that holds the name of the component an
+// entry was logged by, see `Logger.Named`.
+const ComponentField = "component"
+
+// componentLevels maps component names to level overrides. It is replaced,
+// never modified, when an override changes.
+type componentLevels map[string]stdLogger.Level
+
+// Named returns an entry for the named component. Entries logged through it
+// carry the component name in the `component` field and are filtered by the
+// component's effective level instead of `Logger.Level`, see
+// `SetComponentLevel`. Component names are dot separated, e.g. "db.pool".
+func (logger *Logger) Named(name string) *Entry {
+	entry := logger.newEntry()
+	defer logger.releaseEntry(entry)
+	return entry.Named(name)
+}
+
+// Named returns a copy of the entry for a sub-component. The name is
+// appended to the current component name, if any, so
+// `logger.Named("db").Named("pool")` is equivalent to
+// `logger.Named("db.pool")`.
+func (entry *Entry) Named(name string) *Entry {
+	if "" != entry.component && "" != name {
+		name = entry.component + "." + name
+	} else if "" == name {
+		name = entry.component
+	}
+	named := entry.WithField(ComponentField, name)
+	named.component = name
+	return named
+}
+
+// SetComponentLevel overrides the level of a component and its
+// sub-components, e.g. an override for "db" applies to "db.pool" unless
+// "db.pool" has an override of its own. Overrides can be changed at any time.
+func (logger *Logger) SetComponentLevel(name string, level stdLogger.Level) {
+	logger.updateComponentLevels(func(levels componentLevels) {
+		levels[name] = level
+	})
+}
+
+// ClearComponentLevel removes a component's level override, the component
+// falls back to its parent's effective level.
+func (logger *Logger) ClearComponentLevel(name string) {
+	logger.updateComponentLevels(func(levels componentLevels) {
+		delete(levels, name)
+	})
+}
+
+// ComponentLevels returns a copy of the configured component level
+// overrides.
+func (logger *Logger) ComponentLevels() map[string]stdLogger.Level {
+	levels := logger.componentLevels()
+	result := make(map[string]stdLogger.Level, len(levels))
+	for name, level := range levels {
+		result[name] = level
+	}
+	return result
+}
+
+// ComponentLevel returns the effective level of a component. Without an
+// override for the component or any of its parents it is `Logger.Level`.
+func (logger *Logger) ComponentLevel(name string) stdLogger.Level {
+	levels := logger.componentLevels()
+	if 0 == len(levels) {
+		return logger.level()
+	}
+	for {
+		if level, ok := levels[name]; ok {
+			return level
+		}
+		i := strings.LastIndex(name, ".")
+		if i < 0 {
+			return logger.level()
+		}
+		name = name[:i]
+	}
+}
+
+func (logger *Logger) componentLevels() componentLevels {
+	logger = logger.root()
+	levels, _ := logger.components.Load().(componentLevels)
+	return levels
+}
+
+func (logger *Logger) updateComponentLevels(update func(componentLevels)) {
+	logger = logger.root()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	current := logger.componentLevels()
+	levels := make(componentLevels, len(current)+1)
+	for name, level := range current {
+		levels[name] = level
+	}
+	update(levels)
+	logger.components.Store(levels)
+}
+
+// level returns the effective level of the entry.
+func (entry *Entry) level() stdLogger.Level {
+	if "" == entry.component {
+		return entry.Logger.level()
+	}
+	return entry.Logger.ComponentLevel(entry.component)
+}
@@ -0,0 +1,67 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNamedAddsComponentField(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.Named("db").Named("pool").WithField("foo", "bar").Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "db.pool", data.Data[ComponentField])
+		assert.Equal(t, "bar", data.Data["foo"])
+	})
+}
+
+func TestComponentLevelResolution(t *testing.T) {
+	logger := New()
+	logger.SetLevel(InfoLevel)
+	logger.SetComponentLevel("db", DebugLevel)
+	logger.SetComponentLevel("db.pool.stats", ErrorLevel)
+
+	assert.Equal(t, InfoLevel, logger.ComponentLevel("http"))
+	assert.Equal(t, DebugLevel, logger.ComponentLevel("db"))
+	assert.Equal(t, DebugLevel, logger.ComponentLevel("db.pool"))
+	assert.Equal(t, ErrorLevel, logger.ComponentLevel("db.pool.stats"))
+	assert.Equal(t, ErrorLevel, logger.ComponentLevel("db.pool.stats.size"))
+	assert.Equal(t, InfoLevel, logger.ComponentLevel("dbx"))
+
+	logger.ClearComponentLevel("db")
+	assert.Equal(t, InfoLevel, logger.ComponentLevel("db.pool"))
+	assert.Equal(t, map[string]stdLogger.Level{"db.pool.stats": ErrorLevel}, logger.ComponentLevels())
+}
+
+func TestComponentLevelFiltersEntries(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+	logger.SetLevel(InfoLevel)
+
+	db := logger.Named("db.pool")
+	http := logger.Named("http")
+
+	db.Debug("hidden")
+	logger.SetComponentLevel("db", DebugLevel)
+	logger.SetComponentLevel("http", ErrorLevel)
+	db.Debugf("visible %d", 1)
+	db.WithField("conn", 2).Debug("visible")
+	http.Warn("hidden")
+	http.Error("visible")
+	logger.Debug("hidden")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		for _, line := range lines {
+			data := logData{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &data))
+			assert.True(t, strings.HasPrefix(data.Message, "visible"), data.Message)
+		}
+	}
+}
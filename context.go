@@ -0,0 +1,103 @@
+package log
+
+import (
+	"context"
+)
+
+// ContextExtractor pulls log fields out of a context.Context. Extractors are
+// registered on a Logger with `AddContextExtractor` and are run for every
+// entry that carries a context, see `WithContext`.
+type ContextExtractor func(ctx context.Context) Fields
+
+// ContextValue returns a ContextExtractor that copies the value stored in the
+// context under `key` into the field named `field`. Nothing is added if the
+// context does not contain the key.
+//
+//	logger.AddContextExtractor(log.ContextValue(requestIDKey, "request_id"))
+func ContextValue(key interface{}, field string) ContextExtractor {
+	return func(ctx context.Context) Fields {
+		if v := ctx.Value(key); nil != v {
+			return Fields{field: v}
+		}
+		return nil
+	}
+}
+
+// WithContext adds a context to the Entry. Fields are extracted from the
+// context by the logger's registered extractors when the entry is logged.
+func (entry *Entry) WithContext(ctx context.Context) *Entry {
+	return &Entry{
+		Context:   ctx,
+		Data:      entry.Data,
+		Err:       entry.Err,
+		Level:     entry.Level,
+		Logger:    entry.Logger,
+		Message:   entry.Message,
+		Time:      entry.Time,
+		TypedData: entry.TypedData,
+		component: entry.component,
+		keys:      entry.keys,
+	}
+}
+
+// contextData returns the entry data merged with any fields extracted from
+// the entry's context. Fields set explicitly on the entry take precedence
+// over extracted fields. The entry's data map is not modified.
+func (entry *Entry) contextData() Fields {
+	extractors := entry.Logger.contextExtractors()
+	if nil == entry.Context || 0 == len(extractors) {
+		return entry.Data
+	}
+
+	data := make(Fields, len(entry.Data)+len(extractors))
+	for _, extractor := range extractors {
+		for k, v := range extractor(entry.Context) {
+			data[k] = v
+		}
+	}
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	return data
+}
+
+// WithContext adds a context to the log entry, note that it doesn't log until
+// you call Debug, Print, Info, Warn, Error, Fatal or Panic.
+func (logger *Logger) WithContext(ctx context.Context) *Entry {
+	entry := logger.newEntry()
+	defer logger.releaseEntry(entry)
+	return entry.WithContext(ctx)
+}
+
+// AddContextExtractor registers an extractor that is run for every entry
+// logged with a context.
+func (logger *Logger) AddContextExtractor(extractor ContextExtractor) {
+	logger = logger.root()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	extractors := logger.contextExtractors()
+	logger.extractors.Store(append(extractors[:len(extractors):len(extractors)], extractor))
+}
+
+func (logger *Logger) contextExtractors() []ContextExtractor {
+	extractors, _ := logger.extractors.Load().([]ContextExtractor)
+	return extractors
+}
+
+type entryContextKey struct{}
+
+// NewContext returns a copy of ctx that carries entry. Use `FromContext` to
+// retrieve it, e.g. from a request handler.
+func NewContext(ctx context.Context, entry *Entry) context.Context {
+	return context.WithValue(ctx, entryContextKey{}, entry)
+}
+
+// FromContext returns the entry stored in ctx by `NewContext`. If ctx does not
+// carry an entry, a new entry from the standard logger with ctx attached is
+// returned.
+func FromContext(ctx context.Context) *Entry {
+	if entry, ok := ctx.Value(entryContextKey{}).(*Entry); ok && nil != entry {
+		return entry
+	}
+	return std.WithContext(ctx)
+}
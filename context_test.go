@@ -0,0 +1,103 @@
+package log
+
+import (
+	"context"
+	"testing"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type ctxKey string
+
+func TestWithContextExtractsFields(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "abc123")
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(ContextValue(ctxKey("request_id"), "request_id"))
+		log.WithContext(ctx).WithField("foo", "bar").Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "abc123", data.Data["request_id"])
+		assert.Equal(t, "bar", data.Data["foo"])
+	})
+}
+
+func TestWithContextExplicitFieldsWin(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "abc123")
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(ContextValue(ctxKey("request_id"), "request_id"))
+		log.WithField("request_id", "override").WithContext(ctx).Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "override", data.Data["request_id"])
+	})
+}
+
+func TestWithContextMissingValue(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(ContextValue(ctxKey("request_id"), "request_id"))
+		log.WithContext(context.Background()).Info("test")
+	}, func(data logData) {
+		_, ok := data.Data["request_id"]
+		assert.False(t, ok)
+	})
+}
+
+func TestWithContextDoesNotModifyEntry(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "abc123")
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddContextExtractor(ContextValue(ctxKey("request_id"), "request_id"))
+		entry := log.WithField("foo", "bar").WithContext(ctx)
+		entry.Info("test")
+		_, ok := entry.Data["request_id"]
+		assert.False(t, ok)
+	}, func(data logData) {})
+}
+
+func TestWithContextPropagates(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("request_id"), "abc123")
+	logger := New()
+
+	entry := logger.WithContext(ctx)
+	assert.Equal(t, ctx, entry.WithField("foo", "bar").Context)
+	assert.Equal(t, ctx, entry.WithFields(Fields{"foo": "bar"}).Context)
+	assert.Equal(t, ctx, entry.WithError(nil).Context)
+}
+
+type ContextHook struct {
+	Context context.Context
+}
+
+func (hook *ContextHook) Fire(entry *Entry) error {
+	hook.Context = entry.Context
+	return nil
+}
+
+func (hook *ContextHook) Levels() []stdLogger.Level {
+	return []stdLogger.Level{InfoLevel}
+}
+
+func TestHookReceivesContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey("trace_id"), "t-1")
+	hook := new(ContextHook)
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddHook(hook)
+		log.WithContext(ctx).Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "t-1", hook.Context.Value(ctxKey("trace_id")))
+	})
+}
+
+func TestNewContextFromContext(t *testing.T) {
+	logger := New()
+	entry := logger.WithField("foo", "bar")
+
+	ctx := NewContext(context.Background(), entry)
+	assert.Equal(t, entry, FromContext(ctx))
+
+	fallback := FromContext(context.Background())
+	assert.Equal(t, std, fallback.Logger)
+	assert.Equal(t, context.Background(), fallback.Context)
+}
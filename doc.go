@@ -97,6 +97,26 @@ help prevent or mitigate leaking secrets into log files:
       // Output: the secret is '****************'
   }
 
+Secrets added with the package-level `AddSecret` are redacted from the output of every logger.
+Each logger also has its own `Redactor` that supports literal secrets, regular expressions,
+field keys whose values are always masked and partial masking:
+
+  logger := log.New()
+  logger.AddSecret("tenant-secret")
+  logger.Redactor.AddPattern(`AKIA[0-9A-Z]{16}`)
+  logger.Redactor.AddKey("password", "authorization")
+  logger.Redactor.SetKeep(4) // "4111111111111111" is logged as "************1111"
+
+Redaction is applied to the entry's message, error and fields before hooks and formatters run, so
+hooks never see raw secrets. Nested maps, slices and structs are walked, and struct fields can be
+tagged to be masked or omitted:
+
+  type User struct {
+      Name     string
+      Password string `log:"redact"` // logged masked
+      Session  string `log:"-"`      // never logged
+  }
+
 
 
 Output to multiple locations
@@ -2,11 +2,11 @@ package log
 
 import (
 	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
+	"runtime"
 	"sync"
 	"time"
 
@@ -26,6 +26,9 @@ func init() {
 // ErrorKey defines the key when adding errors using WithError.
 var ErrorKey = "error"
 
+// Redacted is the text that replaces sanitized secrets in log output.
+const Redacted = "[REDACTED]"
+
 // Entry is the final or intermediate logging entry. It contains all the
 // fields passed with WithField{,s}. It's finally logged when Debug, Info,
 // Warn, Error, Fatal or Panic is called on it. These objects can be reused
@@ -33,6 +36,9 @@ var ErrorKey = "error"
 type Entry struct {
 	Logger *Logger
 
+	// Contains the context passed by WithContext(context.Context)
+	Context context.Context
+
 	// When formatter is called in entry.log(), an Buffer may be set to entry
 	Buffer *bytes.Buffer
 
@@ -51,34 +57,37 @@ type Entry struct {
 
 	// Time at which the log entry was created
 	Time time.Time
-}
 
-var sanitizeStrings = []string{}
+	// Calling method, captured at the log site if the formatter needs it
+	Caller *runtime.Frame
+
+	// Stack trace of the log site, captured if the formatter needs it
+	Stack []string
+
+	// Hostname written by formatters, the HOSTNAME environment variable or
+	// the system's hostname if empty
+	Host string
+
+	// Contains the typed fields set by With
+	TypedData []Field
+
+	// Component name set by Named, used to resolve the effective level
+	component string
+
+	// Keys of Data in the order the fields were added
+	keys []fieldKey
+
+	// Whether Caller was captured at the log site
+	callerCaptured bool
+}
 
 // NewEntry returns a new logger entry.
 func NewEntry(logger *Logger) *Entry {
 	return &Entry{
-		Logger: logger,
-		// Default is five fields, give a little extra room
-		Data: make(Fields, 5),
-	}
-}
-
-// AddSecret adds strings to the sanitization list.
-func AddSecret(secrets ...string) {
-	newStrings := []string{}
-	for _, secret := range secrets {
-		duplicate := false
-		for _, str := range sanitizeStrings {
-			if str == secret {
-				duplicate = true
-			}
-		}
-		if !duplicate {
-			newStrings = append(newStrings, secret)
-		}
+		Logger: logger.root(),
+		Data:   logger.baseData(),
+		keys:   logger.baseKeys(),
 	}
-	sanitizeStrings = append(sanitizeStrings, newStrings...)
 }
 
 // String returns the string representation from the reader and ultimately the
@@ -88,19 +97,23 @@ func (entry *Entry) String() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	str := string(serialized)
+	str := string(entry.Logger.redact(serialized))
 	return str, nil
 }
 
 // WithError add an error as single field (using the key defined in ErrorKey) to the Entry.
 func (entry *Entry) WithError(err error) *Entry {
 	return &Entry{
-		Data:    entry.Data,
-		Err:     err,
-		Level:   entry.Level,
-		Logger:  entry.Logger,
-		Message: entry.Message,
-		Time:    entry.Time,
+		Context:   entry.Context,
+		Data:      entry.Data,
+		Err:       err,
+		Level:     entry.Level,
+		Logger:    entry.Logger,
+		Message:   entry.Message,
+		Time:      entry.Time,
+		TypedData: entry.TypedData,
+		component: entry.component,
+		keys:      entry.keys,
 	}
 }
 
@@ -120,33 +133,99 @@ func (entry *Entry) WithFields(fields Fields) *Entry {
 		data[k] = v
 	}
 
-	return &Entry{
-		Data:    data,
-		Err:     entry.Err,
-		Level:   entry.Level,
-		Logger:  entry.Logger,
-		Message: entry.Message,
-		Time:    entry.Time,
+	// Small key lists are allocated with the entry.
+	var next *Entry
+	var keys []fieldKey
+	if n := len(entry.keys) + len(fields); n <= len(smallEntry{}.keys) {
+		small := &smallEntry{}
+		next, keys = &small.entry, small.keys[:0:n]
+	} else {
+		next, keys = &Entry{}, make([]fieldKey, 0, n)
+	}
+	keys = append(keys, entry.keys...)
+	// Record the new keys in map order, formatters keeping the field order
+	// sort each batch.
+	for k := range fields {
+		if _, ok := entry.Data[k]; !ok {
+			keys = append(keys, fieldKey{key: k, typed: len(entry.TypedData), batch: len(entry.keys)})
+		}
 	}
+
+	*next = Entry{
+		Context:   entry.Context,
+		Data:      data,
+		Err:       entry.Err,
+		Level:     entry.Level,
+		Logger:    entry.Logger,
+		Message:   entry.Message,
+		Time:      entry.Time,
+		TypedData: entry.TypedData,
+		component: entry.component,
+		keys:      keys,
+	}
+	return next
+}
+
+// smallEntry is an entry allocated together with its keys.
+type smallEntry struct {
+	entry Entry
+	keys  [8]fieldKey
+}
+
+// fieldKey records the position of a field added to `Entry.Data`, typed is
+// the number of typed fields added before it and batch the position of the
+// first key added with it.
+type fieldKey struct {
+	key   string
+	typed int
+	batch int
+}
+
+// batchKeys returns the keys of fields as a single batch.
+func batchKeys(fields Fields) []fieldKey {
+	if 0 == len(fields) {
+		return nil
+	}
+	keys := make([]fieldKey, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, fieldKey{key: k})
+	}
+	return keys
 }
 
 // WithTime overrides the time of the Entry.
 func (entry *Entry) WithTime(t time.Time) *Entry {
-	return &Entry{Logger: entry.Logger, Data: entry.Data, Err: entry.Err, Time: t}
+	return &Entry{Logger: entry.Logger, Context: entry.Context, Data: entry.Data, Err: entry.Err, Time: t, TypedData: entry.TypedData, component: entry.component, keys: entry.keys}
+}
+
+// log consults the sampler before the entry is copied and built, so sampled
+// out entries cost as little as possible.
+func (entry *Entry) log(level logger.Level, msg string) {
+	var suppressed uint64
+	if sampler := entry.Logger.getSampler(); nil != sampler {
+		var ok bool
+		if ok, suppressed = sampler.check(level, msg, time.Now()); !ok {
+			return
+		}
+	}
+	entry.emit(level, msg, suppressed)
 }
 
 // This function is not declared with a pointer value because otherwise
 // race conditions will occur when using multiple goroutines
-func (entry Entry) log(level logger.Level, msg string) {
+func (entry Entry) emit(level logger.Level, msg string, suppressed uint64) {
 	if nil == entry.Logger.Out || entry.Logger.Out == ioutil.Discard {
 		return
 	}
+	if suppressed > 0 {
+		entry.Data = entry.Data.withSuppressed(suppressed)
+	}
 
 	var buffer *bytes.Buffer
 
 	// Default to now, but allow users to override if they want.
 	//
-	// We don't have to worry about polluting future calls to Entry#log()
+	// We don't have to worry about polluting future calls to Entry#emit()
 	// with this assignment because this function is declared with a
 	// non-pointer receiver.
 	if entry.Time.IsZero() {
@@ -155,68 +234,65 @@ func (entry Entry) log(level logger.Level, msg string) {
 
 	entry.Level = level
 	entry.Message = msg
+	entry.Data = entry.contextData()
+	if entry.process() {
+		entry.redact()
+		entry.captureCaller()
 
-	entry.fireHooks()
+		entry.fireHooks()
 
-	buffer = bufferPool.Get().(*bytes.Buffer)
-	buffer.Reset()
-	defer bufferPool.Put(buffer)
-	entry.Buffer = buffer
+		buffer = bufferPool.Get().(*bytes.Buffer)
+		buffer.Reset()
+		defer bufferPool.Put(buffer)
+		entry.Buffer = buffer
 
-	entry.write()
+		entry.write()
 
-	entry.Buffer = nil
+		entry.Buffer = nil
+	}
 
-	// To avoid Entry#log() returning a value that only would make sense for
+	// To avoid Entry#emit() returning a value that only would make sense for
 	// panic() to use in Entry#Panic(), we avoid the allocation by checking
 	// directly here.
-	if level <= PanicLevel && level != FatalLevel {
+	if PanicLevel == level {
 		panic(&entry)
 	}
 }
 
 // This function is not declared with a pointer value because otherwise
 // race conditions will occur when using multiple goroutines
+//
+// Hooks are fired outside the output lock, only the hook list is read under
+// it.
 func (entry Entry) fireHooks() {
 	entry.Logger.mu.Lock()
-	defer entry.Logger.mu.Unlock()
-	err := entry.Logger.Hooks.Fire(entry.Level, &entry)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+	hooks := entry.Logger.Hooks[entry.Level]
+	entry.Logger.mu.Unlock()
+	if err := fireHooks(hooks, &entry); nil != err {
+		entry.Logger.handleHookError(&entry, err)
 	}
 }
 
 func (entry *Entry) write() {
 	serialized, err := entry.Logger.Formatter.Format(entry)
+	if nil == err {
+		serialized = entry.Logger.redact(serialized)
+		if async := entry.Logger.asyncWriter(); nil != async {
+			if async.enqueue(entry.Level, serialized) {
+				// Panic and Fatal entries must not be lost when the program
+				// terminates.
+				if entry.Level <= PanicLevel {
+					async.flush()
+				}
+				return
+			}
+		}
+	}
 	entry.Logger.mu.Lock()
 	defer entry.Logger.mu.Unlock()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to obtain reader, %v\n", err)
 	} else {
-		for _, secret := range sanitizeStrings {
-			if secret == "" {
-				continue
-			}
-
-			// Sanitize secrets
-			serialized = []byte(strings.Replace(
-				string(serialized),
-				secret,
-				"[REDACTED]",
-				-1,
-			))
-
-			// Sanitize JSON-encoded secrets
-			jsonSecret, _ := json.Marshal(secret)
-			// Trim " from json.Marshal
-			jsonSecret = jsonSecret[1 : len(jsonSecret)-1]
-			serialized = []byte(strings.Replace(
-				string(serialized),
-				string(jsonSecret),
-				"[REDACTED]",
-				-1,
-			))
-		}
 		_, err = entry.Logger.Out.Write(serialized)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to write to log, %v\n", err)
@@ -224,16 +300,23 @@ func (entry *Entry) write() {
 	}
 }
 
+// Trace logs a trace-level message using Println.
+func (entry *Entry) Trace(args ...interface{}) {
+	if entry.level() >= TraceLevel {
+		entry.log(TraceLevel, fmt.Sprint(args...))
+	}
+}
+
 // Debug logs a debug-level message using Println.
 func (entry *Entry) Debug(args ...interface{}) {
-	if entry.Logger.level() >= DebugLevel {
+	if entry.level() >= DebugLevel {
 		entry.log(DebugLevel, fmt.Sprint(args...))
 	}
 }
 
 // Info logs a info-level message using Println.
 func (entry *Entry) Info(args ...interface{}) {
-	if entry.Logger.level() >= InfoLevel {
+	if entry.level() >= InfoLevel {
 		entry.log(InfoLevel, fmt.Sprint(args...))
 	}
 }
@@ -245,7 +328,7 @@ func (entry *Entry) Print(args ...interface{}) {
 
 // Warn logs a warn-level message using Println.
 func (entry *Entry) Warn(args ...interface{}) {
-	if entry.Logger.level() >= WarnLevel {
+	if entry.level() >= WarnLevel {
 		entry.log(WarnLevel, fmt.Sprint(args...))
 	}
 }
@@ -257,37 +340,44 @@ func (entry *Entry) Warning(args ...interface{}) {
 
 // Error logs a error-level message using Println.
 func (entry *Entry) Error(args ...interface{}) {
-	if entry.Logger.level() >= ErrorLevel {
+	if entry.level() >= ErrorLevel {
 		entry.log(ErrorLevel, fmt.Sprint(args...))
 	}
 }
 
 // Fatal logs a fatal-level message using Println.
 func (entry *Entry) Fatal(args ...interface{}) {
-	if entry.Logger.level() >= FatalLevel {
+	if entry.level() >= FatalLevel {
 		entry.log(FatalLevel, fmt.Sprint(args...))
 	}
-	Exit(1)
+	entry.Logger.exit(1)
 }
 
 // Panic logs a panic-level message using Println.
 func (entry *Entry) Panic(args ...interface{}) {
-	if entry.Logger.level() >= PanicLevel {
+	if entry.level() >= PanicLevel {
 		entry.log(PanicLevel, fmt.Sprint(args...))
 	}
 	panic(fmt.Sprint(args...))
 }
 
+// Tracef logs a trace-level message using Printf.
+func (entry *Entry) Tracef(format string, args ...interface{}) {
+	if entry.level() >= TraceLevel {
+		entry.Trace(fmt.Sprintf(format, args...))
+	}
+}
+
 // Debugf logs a debug-level message using Printf.
 func (entry *Entry) Debugf(format string, args ...interface{}) {
-	if entry.Logger.level() >= DebugLevel {
+	if entry.level() >= DebugLevel {
 		entry.Debug(fmt.Sprintf(format, args...))
 	}
 }
 
 // Infof logs a info-level message using Printf.
 func (entry *Entry) Infof(format string, args ...interface{}) {
-	if entry.Logger.level() >= InfoLevel {
+	if entry.level() >= InfoLevel {
 		entry.Info(fmt.Sprintf(format, args...))
 	}
 }
@@ -299,7 +389,7 @@ func (entry *Entry) Printf(format string, args ...interface{}) {
 
 // Warnf logs a warn-level message using Printf.
 func (entry *Entry) Warnf(format string, args ...interface{}) {
-	if entry.Logger.level() >= WarnLevel {
+	if entry.level() >= WarnLevel {
 		entry.Warn(fmt.Sprintf(format, args...))
 	}
 }
@@ -311,36 +401,43 @@ func (entry *Entry) Warningf(format string, args ...interface{}) {
 
 // Errorf logs a error-level message using Printf.
 func (entry *Entry) Errorf(format string, args ...interface{}) {
-	if entry.Logger.level() >= ErrorLevel {
+	if entry.level() >= ErrorLevel {
 		entry.Error(fmt.Sprintf(format, args...))
 	}
 }
 
 // Fatalf logs a fatal-level message using Printf.
 func (entry *Entry) Fatalf(format string, args ...interface{}) {
-	if entry.Logger.level() >= FatalLevel {
-		entry.Fatal(fmt.Sprintf(format, args...))
+	if entry.level() >= FatalLevel {
+		entry.log(FatalLevel, fmt.Sprintf(format, args...))
 	}
-	Exit(1)
+	entry.Logger.exit(1)
 }
 
 // Panicf logs a panic-level message using Printf.
 func (entry *Entry) Panicf(format string, args ...interface{}) {
-	if entry.Logger.level() >= PanicLevel {
+	if entry.level() >= PanicLevel {
 		entry.Panic(fmt.Sprintf(format, args...))
 	}
 }
 
+// Traceln logs a trace-level message using Println.
+func (entry *Entry) Traceln(args ...interface{}) {
+	if entry.level() >= TraceLevel {
+		entry.Trace(entry.sprintlnn(args...))
+	}
+}
+
 // Debugln logs a debug-level message using Println.
 func (entry *Entry) Debugln(args ...interface{}) {
-	if entry.Logger.level() >= DebugLevel {
+	if entry.level() >= DebugLevel {
 		entry.Debug(entry.sprintlnn(args...))
 	}
 }
 
 // Infoln logs a info-level message using Println.
 func (entry *Entry) Infoln(args ...interface{}) {
-	if entry.Logger.level() >= InfoLevel {
+	if entry.level() >= InfoLevel {
 		entry.Info(entry.sprintlnn(args...))
 	}
 }
@@ -352,7 +449,7 @@ func (entry *Entry) Println(args ...interface{}) {
 
 // Warnln logs a warn-level message using Println.
 func (entry *Entry) Warnln(args ...interface{}) {
-	if entry.Logger.level() >= WarnLevel {
+	if entry.level() >= WarnLevel {
 		entry.Warn(entry.sprintlnn(args...))
 	}
 }
@@ -364,26 +461,51 @@ func (entry *Entry) Warningln(args ...interface{}) {
 
 // Errorln logs a error-level message using Println.
 func (entry *Entry) Errorln(args ...interface{}) {
-	if entry.Logger.level() >= ErrorLevel {
+	if entry.level() >= ErrorLevel {
 		entry.Error(entry.sprintlnn(args...))
 	}
 }
 
 // Fatalln logs a fatal-level message using Println.
 func (entry *Entry) Fatalln(args ...interface{}) {
-	if entry.Logger.level() >= FatalLevel {
-		entry.Fatal(entry.sprintlnn(args...))
+	if entry.level() >= FatalLevel {
+		entry.log(FatalLevel, entry.sprintlnn(args...))
 	}
-	Exit(1)
+	entry.Logger.exit(1)
 }
 
 // Panicln logs a panic-level message using Println.
 func (entry *Entry) Panicln(args ...interface{}) {
-	if entry.Logger.level() >= PanicLevel {
+	if entry.level() >= PanicLevel {
 		entry.Panic(entry.sprintlnn(args...))
 	}
 }
 
+// Log logs a message at any level, including custom levels, using Print.
+// Fatal and Panic levels exit and panic like `Fatal` and `Panic`.
+func (entry *Entry) Log(level logger.Level, args ...interface{}) {
+	switch level {
+	case FatalLevel:
+		entry.Fatal(args...)
+	case PanicLevel:
+		entry.Panic(args...)
+	default:
+		if entry.level() >= level {
+			entry.log(level, fmt.Sprint(args...))
+		}
+	}
+}
+
+// Logf logs a message at any level using Printf, see `Log`.
+func (entry *Entry) Logf(level logger.Level, format string, args ...interface{}) {
+	entry.Log(level, fmt.Sprintf(format, args...))
+}
+
+// Logln logs a message at any level using Println, see `Log`.
+func (entry *Entry) Logln(level logger.Level, args ...interface{}) {
+	entry.Log(level, entry.sprintlnn(args...))
+}
+
 // Sprintlnn => Sprint no newline. This is to get the behavior of how
 // fmt.Sprintln where spaces are always added between operands, regardless of
 // their type. Instead of vendoring the Sprintln implementation to spare a
@@ -97,20 +97,47 @@ func (p *panickyHook) Fire(entry *Entry) error {
 }
 
 func TestEntryHooksPanic(t *testing.T) {
+	var buffer bytes.Buffer
 	logger := New()
-	logger.Out = &bytes.Buffer{}
+	logger.Out = &buffer
 	logger.Level = InfoLevel
 	logger.Hooks.Add(&panickyHook{})
 
-	defer func() {
-		p := recover()
-		assert.NotNil(t, p)
-		assert.Equal(t, panicMessage, p)
-
-		entry := NewEntry(logger)
-		entry.Info("another message")
-	}()
+	var hookErr error
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		hookErr = err
+	})
 
+	// A panicking hook does not crash the logging call.
 	entry := NewEntry(logger)
 	entry.Info(badMessage)
+	assert.Contains(t, buffer.String(), badMessage)
+
+	errs, ok := hookErr.(HookErrors)
+	if assert.True(t, ok) && assert.Len(t, errs, 1) {
+		assert.Equal(t, panicMessage, errs[0].Panic)
+	}
+
+	hookErr = nil
+	entry.Info("another message")
+	assert.Nil(t, hookErr)
+}
+
+func TestEntryFatalExitsOnce(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	exits := 0
+	logger.ExitFunc = func(code int) {
+		assert.Equal(t, 1, code)
+		exits++
+	}
+
+	entry := NewEntry(logger)
+	entry.Fatal("fatal")
+	entry.Fatalf("fatal %d", 1)
+	entry.Fatalln("fatal", 2)
+	assert.Equal(t, 3, exits)
+	assert.Contains(t, buffer.String(), "fatal 1")
+	assert.Contains(t, buffer.String(), "fatal 2")
 }
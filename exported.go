@@ -1,6 +1,7 @@
 package log
 
 import (
+	"context"
 	"io"
 	"time"
 
@@ -43,6 +44,17 @@ func GetLevel() stdLogger.Level {
 	return std.level()
 }
 
+// SetBaseFields sets the fields added to every entry from the standard
+// logger, see `Logger.SetBaseFields`.
+func SetBaseFields(fields Fields) {
+	std.SetBaseFields(fields)
+}
+
+// SetSampler sets the standard logger sampler.
+func SetSampler(sampler *Sampler) {
+	std.SetSampler(sampler)
+}
+
 // AddHook adds a hook to the standard logger hooks.
 func AddHook(hook Hook) {
 	std.mu.Lock()
@@ -50,6 +62,43 @@ func AddHook(hook Hook) {
 	std.mu.Unlock()
 }
 
+// AddHookWithPriority adds a hook to the standard logger, see
+// `LevelHooks.AddWithPriority`.
+func AddHookWithPriority(hook Hook, priority int) *HookHandle {
+	return std.AddHookWithPriority(hook, priority)
+}
+
+// AddProcessor adds a processor to the standard logger.
+func AddProcessor(processor Processor, priority int) *HookHandle {
+	return std.AddProcessor(processor, priority)
+}
+
+// RemoveHook removes a hook or processor from the standard logger.
+func RemoveHook(handle *HookHandle) {
+	std.RemoveHook(handle)
+}
+
+// SetHookErrorHandler sets the function the standard logger reports hook
+// failures to.
+func SetHookErrorHandler(handler HookErrorHandler) {
+	std.SetHookErrorHandler(handler)
+}
+
+// AddContextExtractor registers a context extractor on the standard logger.
+func AddContextExtractor(extractor ContextExtractor) {
+	std.AddContextExtractor(extractor)
+}
+
+// WithContext creates an entry from the standard logger and adds a context to
+// it. Fields are extracted from the context by the registered extractors when
+// the entry is logged.
+//
+// Note that it doesn't log until you call Debug, Print, Info, Warn, Fatal
+// or Panic on the Entry it returns.
+func WithContext(ctx context.Context) *Entry {
+	return std.WithContext(ctx)
+}
+
 // WithError creates an entry from the standard logger and adds an error to it, using the value defined in ErrorKey as key.
 func WithError(err error) *Entry {
 	return std.WithError(err)
@@ -74,6 +123,28 @@ func WithFields(fields Fields) *Entry {
 	return std.WithFields(fields)
 }
 
+// With creates an entry from the standard logger and adds typed fields to it.
+//
+// Note that it doesn't log until you call Debug, Print, Info, Warn, Fatal
+// or Panic on the Entry it returns.
+func With(fields ...Field) *Entry {
+	return std.With(fields...)
+}
+
+// Named creates an entry for the named component from the standard logger.
+//
+// Note that it doesn't log until you call Debug, Print, Info, Warn, Fatal
+// or Panic on the Entry it returns.
+func Named(name string) *Entry {
+	return std.Named(name)
+}
+
+// SetComponentLevel overrides the level of a component of the standard
+// logger.
+func SetComponentLevel(name string, level stdLogger.Level) {
+	std.SetComponentLevel(name, level)
+}
+
 // WithTime creats an entry from the standard logger and overrides the time of
 // logs generated with it.
 //
@@ -83,6 +154,11 @@ func WithTime(t time.Time) *Entry {
 	return std.WithTime(t)
 }
 
+// Trace logs a message at level Trace on the standard logger.
+func Trace(args ...interface{}) {
+	std.Trace(args...)
+}
+
 // Debug logs a message at level Debug on the standard logger.
 func Debug(args ...interface{}) {
 	std.Debug(args...)
@@ -123,6 +199,11 @@ func Fatal(args ...interface{}) {
 	std.Fatal(args...)
 }
 
+// Tracef logs a message at level Trace on the standard logger.
+func Tracef(format string, args ...interface{}) {
+	std.Tracef(format, args...)
+}
+
 // Debugf logs a message at level Debug on the standard logger.
 func Debugf(format string, args ...interface{}) {
 	std.Debugf(format, args...)
@@ -163,6 +244,11 @@ func Fatalf(format string, args ...interface{}) {
 	std.Fatalf(format, args...)
 }
 
+// Traceln logs a message at level Trace on the standard logger.
+func Traceln(args ...interface{}) {
+	std.Traceln(args...)
+}
+
 // Debugln logs a message at level Debug on the standard logger.
 func Debugln(args ...interface{}) {
 	std.Debugln(args...)
@@ -198,6 +284,22 @@ func Panicln(args ...interface{}) {
 	std.Panicln(args...)
 }
 
+// Log logs a message at any level, including custom levels, on the standard
+// logger.
+func Log(level stdLogger.Level, args ...interface{}) {
+	std.Log(level, args...)
+}
+
+// Logf logs a message at any level on the standard logger.
+func Logf(level stdLogger.Level, format string, args ...interface{}) {
+	std.Logf(level, format, args...)
+}
+
+// Logln logs a message at any level on the standard logger.
+func Logln(level stdLogger.Level, args ...interface{}) {
+	std.Logln(level, args...)
+}
+
 // Fatalln logs a message at level Fatal on the standard logger then the process will exit with status set to 1.
 func Fatalln(args ...interface{}) {
 	std.Fatalln(args...)
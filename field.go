@@ -0,0 +1,292 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// FieldType identifies how a typed Field's value is stored.
+type FieldType uint8
+
+// Field types.
+const (
+	UnknownType FieldType = iota
+	AnyType
+	BoolType
+	DurationType
+	ErrorType
+	Float64Type
+	Int64Type
+	StringType
+	TimeType
+	Uint64Type
+)
+
+// Field is a typed key/value pair added to an entry with `Entry.With`. Unlike
+// `Fields`, typed fields are stored in a slice and scalar values are not
+// boxed into an interface, so adding them does not allocate a map or copy
+// the entry's existing fields.
+//
+//	logger.With(log.String("user", id), log.Int("attempt", n)).Info("login")
+type Field struct {
+	Key       string
+	Type      FieldType
+	Integer   int64
+	String    string
+	Interface interface{}
+}
+
+// Any returns a field holding an arbitrary value. Prefer the typed
+// constructors, Any boxes the value.
+func Any(key string, value interface{}) Field {
+	return Field{Key: key, Type: AnyType, Interface: value}
+}
+
+// Bool returns a boolean field.
+func Bool(key string, value bool) Field {
+	var i int64
+	if value {
+		i = 1
+	}
+	return Field{Key: key, Type: BoolType, Integer: i}
+}
+
+// Duration returns a duration field. Durations are formatted as strings,
+// e.g. "1.5s".
+func Duration(key string, value time.Duration) Field {
+	return Field{Key: key, Type: DurationType, Integer: int64(value)}
+}
+
+// Err returns a field holding an error under the `ErrorKey` key. Use
+// `NamedErr` to choose the key. A nil error is logged as null.
+func Err(err error) Field {
+	return NamedErr(ErrorKey, err)
+}
+
+// NamedErr returns a field holding an error.
+func NamedErr(key string, err error) Field {
+	return Field{Key: key, Type: ErrorType, Interface: err}
+}
+
+// Float64 returns a float field.
+func Float64(key string, value float64) Field {
+	return Field{Key: key, Type: Float64Type, Integer: int64(math.Float64bits(value))}
+}
+
+// Int returns an integer field.
+func Int(key string, value int) Field {
+	return Int64(key, int64(value))
+}
+
+// Int64 returns an integer field.
+func Int64(key string, value int64) Field {
+	return Field{Key: key, Type: Int64Type, Integer: value}
+}
+
+// String returns a string field.
+func String(key string, value string) Field {
+	return Field{Key: key, Type: StringType, String: value}
+}
+
+// Time returns a time field, formatted as RFC 3339 with nanoseconds.
+func Time(key string, value time.Time) Field {
+	if value.Year() < 1678 || value.Year() > 2261 {
+		// Outside the range of UnixNano.
+		return Any(key, value)
+	}
+	return Field{Key: key, Type: TimeType, Integer: value.UnixNano(), Interface: value.Location()}
+}
+
+// Uint64 returns an unsigned integer field.
+func Uint64(key string, value uint64) Field {
+	return Field{Key: key, Type: Uint64Type, Integer: int64(value)}
+}
+
+// Value returns the field's value as an interface.
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case BoolType:
+		return 1 == f.Integer
+	case DurationType:
+		return time.Duration(f.Integer)
+	case Float64Type:
+		return f.float()
+	case Int64Type:
+		return f.Integer
+	case StringType:
+		return f.String
+	case TimeType:
+		return f.time()
+	case Uint64Type:
+		return uint64(f.Integer)
+	}
+	return f.Interface
+}
+
+func (f Field) time() time.Time {
+	t := time.Unix(0, f.Integer)
+	if loc, ok := f.Interface.(*time.Location); ok && nil != loc {
+		t = t.In(loc)
+	}
+	return t
+}
+
+func (f Field) float() float64 {
+	return math.Float64frombits(uint64(f.Integer))
+}
+
+// appendJSON appends the field's value as the JSON, text and std formatters
+// write it. Scalars are encoded directly, strings are escaped like the
+// strings of map fields.
+func (f Field) appendJSON(b []byte, escapeHTML bool) ([]byte, error) {
+	switch f.Type {
+	case BoolType:
+		return strconv.AppendBool(b, 1 == f.Integer), nil
+	case DurationType:
+		return appendJSONString(b, quoteASCII(time.Duration(f.Integer).String()), escapeHTML), nil
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && nil != err {
+			return appendJSONString(b, quoteASCII(err.Error()), escapeHTML), nil
+		}
+		return append(b, "null"...), nil
+	case Float64Type:
+		v := f.float()
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			// Not representable as a JSON number.
+			return appendJSONString(b, strconv.FormatFloat(v, 'g', -1, 64), escapeHTML), nil
+		}
+		return strconv.AppendFloat(b, v, 'g', -1, 64), nil
+	case Int64Type:
+		return strconv.AppendInt(b, f.Integer, 10), nil
+	case StringType:
+		return appendJSONString(b, quoteASCII(f.String), escapeHTML), nil
+	case TimeType:
+		b = append(b, '"')
+		b = f.time().AppendFormat(b, time.RFC3339Nano)
+		return append(b, '"'), nil
+	case Uint64Type:
+		return strconv.AppendUint(b, uint64(f.Integer), 10), nil
+	}
+
+	var v interface{}
+	switch tv := f.Interface.(type) {
+	case string:
+		return appendJSONString(b, quoteASCII(tv), escapeHTML), nil
+	case error:
+		v = tv.Error()
+	default:
+		v = tv
+	}
+	buf := &bytes.Buffer{}
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(escapeHTML)
+	if err := encoder.Encode(v); nil != err {
+		return b, err
+	}
+	return append(b, bytes.TrimRight(buf.Bytes(), "\n")...), nil
+}
+
+// appendTTY appends the field's value as the TTY text formatter writes it,
+// strings are quoted.
+func (f Field) appendTTY(b []byte) []byte {
+	switch f.Type {
+	case BoolType, Int64Type, Uint64Type:
+		b, _ = f.appendJSON(b, false)
+		return b
+	case Float64Type:
+		if v := f.float(); !math.IsNaN(v) && !math.IsInf(v, 0) {
+			return strconv.AppendFloat(b, v, 'g', -1, 64)
+		}
+	case ErrorType:
+		if err, ok := f.Interface.(error); !ok || nil == err {
+			return append(b, "<nil>"...)
+		}
+	case AnyType, UnknownType:
+		if _, ok := f.Interface.(string); !ok {
+			return append(b, fmt.Sprint(f.Interface)...)
+		}
+	}
+	b = append(b, '"')
+	b = append(b, quoteASCII(f.text())...)
+	return append(b, '"')
+}
+
+// appendText appends the field's value as text.
+func (f Field) appendText(b []byte) []byte {
+	switch f.Type {
+	case BoolType:
+		return strconv.AppendBool(b, 1 == f.Integer)
+	case DurationType:
+		return append(b, time.Duration(f.Integer).String()...)
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && nil != err {
+			return append(b, err.Error()...)
+		}
+		return append(b, "<nil>"...)
+	case Float64Type:
+		return strconv.AppendFloat(b, f.float(), 'g', -1, 64)
+	case Int64Type:
+		return strconv.AppendInt(b, f.Integer, 10)
+	case StringType:
+		return append(b, f.String...)
+	case TimeType:
+		return f.time().AppendFormat(b, time.RFC3339Nano)
+	case Uint64Type:
+		return strconv.AppendUint(b, uint64(f.Integer), 10)
+	}
+	return append(b, fmt.Sprint(f.Interface)...)
+}
+
+// text returns the field's value as text.
+func (f Field) text() string {
+	if StringType == f.Type {
+		return f.String
+	}
+	return string(f.appendText(nil))
+}
+
+// With returns a copy of the entry with typed fields added. Fields with the
+// same key as an existing typed field replace it in the output.
+func (entry *Entry) With(fields ...Field) *Entry {
+	typed := make([]Field, 0, len(entry.TypedData)+len(fields))
+	typed = append(typed, entry.TypedData...)
+	typed = append(typed, fields...)
+	return &Entry{
+		Context:   entry.Context,
+		Data:      entry.Data,
+		Err:       entry.Err,
+		Level:     entry.Level,
+		Logger:    entry.Logger,
+		Message:   entry.Message,
+		Time:      entry.Time,
+		TypedData: typed,
+		component: entry.component,
+		keys:      entry.keys,
+	}
+}
+
+// With creates an entry with typed fields, see `Entry.With`.
+func (logger *Logger) With(fields ...Field) *Entry {
+	entry := logger.newEntry()
+	defer logger.releaseEntry(entry)
+	return entry.With(fields...)
+}
+
+// AllData returns the entry's map and typed fields merged into a new
+// `Fields` map. Typed fields take precedence over map fields with the same
+// key. Hooks and formatters that only know about `Data` can use it to see
+// every field.
+func (entry *Entry) AllData() Fields {
+	data := make(Fields, len(entry.Data)+len(entry.TypedData))
+	for k, v := range entry.Data {
+		data[k] = v
+	}
+	for _, f := range entry.TypedData {
+		data[f.Key] = f.Value()
+	}
+	return data
+}
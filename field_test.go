@@ -0,0 +1,211 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldValues(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	err := errors.New("boom")
+
+	assert.Equal(t, "value", String("k", "value").Value())
+	assert.Equal(t, int64(42), Int("k", 42).Value())
+	assert.Equal(t, int64(-42), Int64("k", -42).Value())
+	assert.Equal(t, uint64(math.MaxUint64), Uint64("k", math.MaxUint64).Value())
+	assert.Equal(t, 1.5, Float64("k", 1.5).Value())
+	assert.Equal(t, true, Bool("k", true).Value())
+	assert.Equal(t, false, Bool("k", false).Value())
+	assert.Equal(t, time.Second, Duration("k", time.Second).Value())
+	assert.True(t, now.Equal(Time("k", now).Value().(time.Time)))
+	assert.Equal(t, err, Err(err).Value())
+	assert.Equal(t, ErrorKey, Err(err).Key)
+	assert.Equal(t, []int{1}, Any("k", []int{1}).Value())
+
+	ancient := time.Date(1000, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, ancient, Time("k", ancient).Value())
+}
+
+func TestWithTypedFieldsJSON(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithField("map", "field").With(
+			String("string", "value"),
+			Int("int", 42),
+			Uint64("uint", 7),
+			Float64("float", 1.5),
+			Float64("nan", math.NaN()),
+			Bool("bool", true),
+			Duration("duration", 1500*time.Millisecond),
+			Time("time", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+			Err(errors.New("boom")),
+			NamedErr("nil_error", nil),
+			Any("any", map[string]int{"a": 1}),
+		).Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "field", data.Data["map"])
+		assert.Equal(t, "value", data.Data["string"])
+		assert.Equal(t, 42.0, data.Data["int"])
+		assert.Equal(t, 7.0, data.Data["uint"])
+		assert.Equal(t, 1.5, data.Data["float"])
+		assert.Equal(t, "NaN", data.Data["nan"])
+		assert.Equal(t, true, data.Data["bool"])
+		assert.Equal(t, "1.5s", data.Data["duration"])
+		assert.Equal(t, "2024-01-02T03:04:05Z", data.Data["time"])
+		assert.Equal(t, "boom", data.Data[ErrorKey])
+		assert.Nil(t, data.Data["nil_error"])
+		assert.Equal(t, map[string]interface{}{"a": 1.0}, data.Data["any"])
+	})
+}
+
+func TestWithTypedFieldsText(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTTY: true}
+	logger.With(String("name", "value"), Int("count", 3), Bool("ok", true)).Info("test")
+	assert.Contains(t, buffer.String(), `name="value"`)
+	assert.Contains(t, buffer.String(), "count=3")
+	assert.Contains(t, buffer.String(), "ok=true")
+}
+
+func TestWithDoesNotModifyParent(t *testing.T) {
+	logger := New()
+	parent := logger.With(String("a", "1"))
+	child := parent.With(String("b", "2"))
+	sibling := parent.With(String("c", "3"))
+
+	assert.Len(t, parent.TypedData, 1)
+	assert.Equal(t, "b", child.TypedData[1].Key)
+	assert.Equal(t, "c", sibling.TypedData[1].Key)
+
+	// Typed fields survive the other entry constructors.
+	entry := child.WithField("m", 1).WithError(errors.New("e")).WithTime(time.Now()).Named("db")
+	assert.Len(t, entry.TypedData, 2)
+}
+
+func TestEntryAllData(t *testing.T) {
+	entry := New().WithField("a", 1).WithField("b", 2).With(Int("b", 3), String("c", "x"))
+	assert.Equal(t, Fields{"a": 1, "b": int64(3), "c": "x"}, entry.AllData())
+}
+
+func TestRedactTypedFields(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+	logger.AddSecret("hunter2")
+	logger.Redactor.AddKey("token")
+
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+
+	logger.With(
+		String("password", "is hunter2"),
+		Int("token", 12345),
+		Err(fmt.Errorf("login with hunter2: %w", errors.New("denied"))),
+		Any("user", redactUser{Name: "bob", Password: "secret"}),
+	).Info("test")
+
+	out := buffer.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "12345")
+	assert.NotContains(t, out, "secret")
+
+	if assert.Len(t, hook.Entries, 1) {
+		for _, f := range hook.Entries[0].TypedData {
+			assert.NotContains(t, fmt.Sprintf("%v", f.Value()), "hunter2", f.Key)
+		}
+	}
+}
+
+var entrySink *Entry
+
+func TestWithAllocations(t *testing.T) {
+	logger := New()
+	base := logger.WithFields(Fields{"a": 1, "b": 2, "c": 3, "d": 4})
+
+	typed := testing.AllocsPerRun(100, func() {
+		entrySink = base.With(String("key", "value"), Int("count", 1))
+	})
+	mapped := testing.AllocsPerRun(100, func() {
+		entrySink = base.WithFields(Fields{"key": "value", "count": 1})
+	})
+	assert.True(t, typed <= 2, "With allocated %v times", typed)
+	assert.True(t, typed < mapped, "With %v, WithFields %v", typed, mapped)
+}
+
+func BenchmarkWithTypedFields(b *testing.B) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	base := logger.With(String("service", "api"), String("region", "eu"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entrySink = base.With(Int("attempt", i), Duration("elapsed", time.Millisecond))
+	}
+}
+
+func BenchmarkWithFieldsMap(b *testing.B) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	base := logger.WithFields(Fields{"service": "api", "region": "eu"})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		entrySink = base.WithFields(Fields{"attempt": i, "elapsed": time.Millisecond})
+	}
+}
+
+func TestFieldText(t *testing.T) {
+	assert.Equal(t, "x", String("k", "x").text())
+	assert.Equal(t, "42", Int("k", 42).text())
+	assert.True(t, strings.HasPrefix(Duration("k", time.Second).text(), "1s"))
+}
+
+func TestTypedFieldKeyClashes(t *testing.T) {
+	logLine := func(formatter Formatter) string {
+		var buffer bytes.Buffer
+		logger := New()
+		logger.Out = &buffer
+		logger.Formatter = formatter
+		logger.WithField("level", "map").
+			With(String("msg", "typed"), Int("data.count", 2)).
+			Info("test")
+		return buffer.String()
+	}
+
+	text := logLine(&TextFormatter{DisableTTY: true, FieldMap: FieldMap{LabelData: ""}})
+	assert.Contains(t, text, ` level="info" msg="test" `)
+	assert.Contains(t, text, ` data.count=2 data.level="map" data.msg="typed" `)
+
+	std := logLine(&StdFormatter{})
+	assert.Contains(t, std, ` data.count=2 data.level="map" data.msg="typed" `)
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithField("level", "map").With(String("msg", "typed"), Int("data.count", 2)).Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "test", data.Message)
+		assert.Equal(t, "info", data.Level)
+		assert.Equal(t, Fields{"count": 2.0, "level": "map", "msg": "typed"}, Fields(data.Data))
+	})
+}
+
+func TestAppendJSONString(t *testing.T) {
+	for _, s := range []string{"", "plain", "q\"b\\s/", "<a&b>", "\n\r\t\x00\x1f\x7f", "é  ", "bad\xffutf8"} {
+		for _, escapeHTML := range []bool{false, true} {
+			buf := &bytes.Buffer{}
+			encoder := json.NewEncoder(buf)
+			encoder.SetEscapeHTML(escapeHTML)
+			assert.NoError(t, encoder.Encode(s))
+			assert.Equal(t, strings.TrimSuffix(buf.String(), "\n"), string(appendJSONString(nil, s, escapeHTML)), "%q", s)
+		}
+	}
+}
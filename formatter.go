@@ -3,12 +3,12 @@ package log
 import (
 	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"os"
-	"path"
-	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // RFC3339Milli defines an RFC3339 date format with miliseconds
@@ -34,6 +34,97 @@ const (
 	LabelTrace  = "trace"
 )
 
+// defaultKeyOrder is the order default fields are written in by the text
+// and logfmt formatters.
+var defaultKeyOrder = []FieldLabel{
+	LabelTime,
+	LabelLevel,
+	LabelMsg,
+	LabelError,
+	LabelData,
+	LabelCaller,
+	LabelHost,
+	LabelTrace,
+}
+
+// keyOrder returns the default field labels in order, the labels in order
+// first followed by the others in their order in defaults. Unknown and
+// repeated labels are ignored.
+func keyOrder(order, defaults []FieldLabel) []FieldLabel {
+	if 0 == len(order) {
+		return defaults
+	}
+	result := make([]FieldLabel, 0, len(defaults))
+	for _, label := range order {
+		for _, known := range defaults {
+			if label == known && !containsLabel(result, label) {
+				result = append(result, label)
+			}
+		}
+	}
+	for _, label := range defaults {
+		if !containsLabel(result, label) {
+			result = append(result, label)
+		}
+	}
+	return result
+}
+
+func containsLabel(labels []FieldLabel, label FieldLabel) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// dataKeys returns the keys of an entry's formatted data fields, the keys of
+// data and of the typed fields that are not in data. If keepOrder is set
+// they are in the order the fields were added to the entry, followed by
+// fields added otherwise, e.g. by hooks, sorted. Otherwise they are sorted.
+func dataKeys(entry *Entry, data map[string]interface{}, typed []Field, keepOrder bool) []string {
+	keys := make([]string, 0, len(data)+len(typed))
+	var seen map[string]bool
+	if keepOrder {
+		seen = make(map[string]bool, len(data))
+		add := func(k string) {
+			if _, ok := data[k]; (ok || 0 <= typedIndex(typed, k)) && !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+		typed := 0
+		for i := 0; i < len(entry.keys); {
+			for ; typed < entry.keys[i].typed && typed < len(entry.TypedData); typed++ {
+				add(entry.TypedData[typed].Key)
+			}
+			// Keys added together are recorded in map order.
+			batch, start := entry.keys[i].batch, len(keys)
+			for ; i < len(entry.keys) && entry.keys[i].batch == batch; i++ {
+				add(entry.keys[i].key)
+			}
+			sort.Strings(keys[start:])
+		}
+		for ; typed < len(entry.TypedData); typed++ {
+			add(entry.TypedData[typed].Key)
+		}
+	}
+	ordered := len(keys)
+	for k := range data {
+		if !seen[k] {
+			keys = append(keys, k)
+		}
+	}
+	for _, f := range typed {
+		if !seen[f.Key] {
+			keys = append(keys, f.Key)
+		}
+	}
+	sort.Strings(keys[ordered:])
+	return keys
+}
+
 func (f FieldMap) resolve(fieldLabel FieldLabel) string {
 	if definedLabel, ok := f[fieldLabel]; ok {
 		return definedLabel
@@ -51,7 +142,11 @@ type logData struct {
 	LabelTime   string   `json:"-"`
 	LabelTrace  string   `json:"-"`
 	Color       colors   `json:"-"`
+	DataLines   []string `json:"-"`
+	DataKeys    []string `json:"-"`
+	DataText    string   `json:"-"`
 	ErrData     []string `json:"-"`
+	LevelWidth  int      `json:"-"`
 
 	Caller    string                 `json:"caller,omitempty"`
 	Data      map[string]interface{} `json:"data,omitempty"`
@@ -61,9 +156,14 @@ type logData struct {
 	Message   string                 `json:"msg,omitempty"`
 	Timestamp string                 `json:"time,omitempty"`
 	Trace     []string               `json:"trace,omitempty"`
+
+	// Typed holds the entry's typed fields, keyed like Data. They are not
+	// in Data, formatters encode them directly.
+	Typed []Field `json:"-"`
 }
 
-// UnmarshalJSON implements the json.Unmarshaler interface.
+// UnmarshalJSON implements the json.Unmarshaler interface. Values of an
+// unexpected type are ignored.
 func (l *logData) UnmarshalJSON(d []byte) error {
 	data := map[string]interface{}{}
 
@@ -72,105 +172,51 @@ func (l *logData) UnmarshalJSON(d []byte) error {
 		return err
 	}
 
-	if _, ok := data["caller"]; ok {
-		l.Caller = data["caller"].(string)
+	if v, ok := data["caller"].(string); ok {
+		l.Caller = v
 	}
-	if _, ok := data["data"]; ok {
+	if v, ok := data["data"].(map[string]interface{}); ok {
 		if nil == l.Data {
 			l.Data = map[string]interface{}{}
 		}
-		for k, v := range data["data"].(map[string]interface{}) {
-			if e, ok := v.(error); ok {
-				l.Data[k] = e.(error)
-			} else {
-				l.Data[k] = v
-			}
+		for k, v := range v {
+			l.Data[k] = v
 		}
-		//l.Data = data["data"].(map[string]interface{})
 	}
-	if _, ok := data["error"]; ok && "" != data["error"] {
-		l.Err = fmt.Errorf(data["error"].(string))
-	}
-	if _, ok := data["host"]; ok {
-		l.Hostname = data["host"].(string)
+	switch v := data["error"].(type) {
+	case nil:
+	case string:
+		if "" != v {
+			l.Err = errors.New(v)
+		}
+	default:
+		l.Err = v
 	}
-	if _, ok := data["level"]; ok {
-		l.Level = data["level"].(string)
+	if v, ok := data["host"].(string); ok {
+		l.Hostname = v
 	}
-	if _, ok := data["msg"]; ok {
-		l.Message = data["msg"].(string)
+	if v, ok := data["level"].(string); ok {
+		l.Level = v
 	}
-	if _, ok := data["time"]; ok {
-		l.Timestamp = data["time"].(string)
+	if v, ok := data["msg"].(string); ok {
+		l.Message = v
 	}
-	if _, ok := data["trace"]; ok {
-		l.Trace = data["trace"].([]string)
+	if v, ok := data["time"].(string); ok {
+		l.Timestamp = v
 	}
-
-	return nil
-}
-
-// SetCallerLevel will adjust the relative caller level in log output.
-func SetCallerLevel(level int) {
-	callerLevel = level
-}
-
-var callerLevel int
-
-func getCaller() string {
-	caller := ""
-	a := 0
-	for {
-		if pc, file, line, ok := runtime.Caller(a); ok {
-			if !strings.Contains(strings.ToLower(file), "github.com/bdlm/log") ||
-				strings.HasSuffix(strings.ToLower(file), "_test.go") {
-				if 0 == callerLevel {
-					caller = fmt.Sprintf("%s:%d %s", path.Base(file), line, runtime.FuncForPC(pc).Name())
-				} else {
-					if pc2, file2, line2, ok := runtime.Caller(a + callerLevel); ok {
-						caller = fmt.Sprintf("%s:%d %s", path.Base(file2), line2, runtime.FuncForPC(pc2).Name())
-					} else {
-						caller = fmt.Sprintf("%s:%d %s", path.Base(file), line, runtime.FuncForPC(pc).Name())
-					}
-				}
-				break
+	if v, ok := data["trace"].([]interface{}); ok {
+		l.Trace = make([]string, 0, len(v))
+		for _, frame := range v {
+			if frame, ok := frame.(string); ok {
+				l.Trace = append(l.Trace, frame)
 			}
-		} else {
-			break
 		}
-		a++
 	}
-	return caller
-}
 
-func getTrace() []string {
-	trace := []string{}
-	a := 0
-	for {
-		if pc, file, line, ok := runtime.Caller(a); ok {
-			if !strings.Contains(strings.ToLower(file), "github.com/bdlm/log") ||
-				strings.HasSuffix(strings.ToLower(file), "_test.go") {
-				if 0 == callerLevel {
-					trace = append(trace, fmt.Sprintf("%s:%d %s", path.Base(file), line, runtime.FuncForPC(pc).Name()))
-				} else {
-					if pc2, file2, line2, ok := runtime.Caller(a + callerLevel); ok {
-						trace = append(trace, fmt.Sprintf("%s:%d %s", path.Base(file2), line2, runtime.FuncForPC(pc2).Name()))
-					} else {
-						trace = append(trace, fmt.Sprintf("%s:%d %s", path.Base(file), line, runtime.FuncForPC(pc).Name()))
-					}
-				}
-			}
-		} else {
-			break
-		}
-		a++
-	}
-	if len(trace) > 2 {
-		trace = trace[:len(trace)-2]
-	}
-	return trace
+	return nil
 }
 
+// The TTY color variables are shared by every formatter without a `Theme`.
 var (
 	// DEFAULTColor is the default TTY 'level' color.
 	DEFAULTColor = "\033[38;5;46m"
@@ -184,6 +230,8 @@ var (
 	WARNColor = "\033[38;5;226m"
 	// DEBUGColor is the TTY 'level' color for debug messages.
 	DEBUGColor = "\033[38;5;245m"
+	// TRACEColor is the TTY 'level' color for trace messages.
+	TRACEColor = "\033[38;5;240m"
 
 	// CallerColor is the TTY caller color.
 	CallerColor = "\033[38;5;244m"
@@ -226,27 +274,34 @@ func escape(data interface{}, escapeHTML bool) string {
 	return result
 }
 
-// getData is a helper function that extracts log data from the Entry.
+// getData is a helper function that extracts log data from the Entry,
+// including the caller and the stack trace.
 func getData(entry *Entry, fieldMap FieldMap, escapeHTML, isTTY bool) *logData {
-	var levelColor string
+	return getEntryData(entry, fieldMap, escapeHTML, isTTY, true, true)
+}
 
+// getEntryData extracts log data from the Entry. The caller and the stack
+// trace are only resolved if requested.
+func getEntryData(entry *Entry, fieldMap FieldMap, escapeHTML, isTTY, caller, trace bool) *logData {
 	data := &logData{
-		Caller:    getCaller(),
 		Data:      map[string]interface{}{},
 		Err:       entry.Err,
 		ErrData:   []string{},
-		Hostname:  os.Getenv("HOSTNAME"),
+		Hostname:  entry.Host,
 		Level:     LevelString(entry.Level),
 		Message:   entry.Message,
 		Timestamp: entry.Time.Format(RFC3339Milli),
-		Trace:     getTrace(),
+		Trace:     []string{},
+	}
+	if caller {
+		data.Caller = entry.callerString()
+	}
+	if trace {
+		data.Trace = entry.stackTrace()
 	}
 
 	if data.Hostname == "" {
-		hostname, err := os.Hostname()
-		if err == nil {
-			data.Hostname = hostname
-		}
+		data.Hostname = localHostname()
 	}
 
 	data.LabelCaller = fieldMap.resolve(LabelCaller)
@@ -259,31 +314,7 @@ func getData(entry *Entry, fieldMap FieldMap, escapeHTML, isTTY bool) *logData {
 	data.LabelTrace = fieldMap.resolve(LabelTrace)
 
 	if isTTY {
-		switch entry.Level {
-		case DebugLevel:
-			levelColor = DEBUGColor
-		case WarnLevel:
-			levelColor = WARNColor
-		case ErrorLevel:
-			levelColor = ERRORColor
-		case FatalLevel:
-			levelColor = FATALColor
-		case PanicLevel:
-			levelColor = PANICColor
-		default:
-			levelColor = DEFAULTColor
-		}
-		data.Color = colors{
-			Caller:    CallerColor,
-			DataLabel: DataLabelColor,
-			DataValue: DataValueColor,
-			Err:       ERRORColor,
-			Hostname:  HostnameColor,
-			Level:     levelColor,
-			Reset:     ResetColor,
-			Timestamp: TimestampColor,
-			Trace:     TraceColor,
-		}
+		data.Color = legacyColors(entry.Level)
 	}
 
 	remapData(entry, fieldMap, data)
@@ -291,18 +322,157 @@ func getData(entry *Entry, fieldMap FieldMap, escapeHTML, isTTY bool) *logData {
 	return data
 }
 
+// localHostname returns the HOSTNAME environment variable or the host name
+// reported by the kernel.
+func localHostname() string {
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	hostname, err := os.Hostname()
+	if err == nil {
+		return hostname
+	}
+	return ""
+}
+
 func remapData(entry *Entry, fieldMap FieldMap, data *logData) {
 	for k, v := range entry.Data {
-		switch k {
+		switch v := v.(type) {
+		case string:
+			data.Data[dataKey(k, fieldMap)] = quoteASCII(v)
 		default:
-			switch v := v.(type) {
-			case string:
-				data.Data[strings.TrimPrefix(k, fieldMap.resolve(LabelData)+".")] = strings.Trim(strconv.QuoteToASCII(fmt.Sprintf("%v", v)), `"`)
+			data.Data[dataKey(k, fieldMap)] = v
+		}
+	}
+	if 0 == len(entry.TypedData) {
+		return
+	}
+	// Typed fields replace map fields and earlier typed fields with the
+	// same key.
+	data.Typed = make([]Field, 0, len(entry.TypedData))
+	for _, f := range entry.TypedData {
+		f.Key = dataKey(f.Key, fieldMap)
+		delete(data.Data, f.Key)
+		if i := typedIndex(data.Typed, f.Key); 0 <= i {
+			data.Typed[i] = f
+			continue
+		}
+		data.Typed = append(data.Typed, f)
+	}
+}
+
+// dataKey returns the key a data field is written with. The data label
+// prefix added by `prefixFieldClashes` is removed. If data fields are not
+// nested under a data label, keys clashing with a default field are
+// prefixed with "data." so they don't overwrite it.
+func dataKey(key string, fieldMap FieldMap) string {
+	labelData := fieldMap.resolve(LabelData)
+	key = strings.TrimPrefix(key, labelData+".")
+	if "" == labelData && isDefaultKey(key, fieldMap) {
+		return LabelData + "." + key
+	}
+	return key
+}
+
+// isDefaultKey reports whether key is the key of a default field.
+func isDefaultKey(key string, fieldMap FieldMap) bool {
+	for _, label := range defaultKeyOrder {
+		if LabelData != label && key == fieldMap.resolve(label) {
+			return true
+		}
+	}
+	return false
+}
+
+// typedIndex returns the index of the typed field with the key, or -1.
+func typedIndex(typed []Field, key string) int {
+	for i := range typed {
+		if key == typed[i].Key {
+			return i
+		}
+	}
+	return -1
+}
+
+// appendTextData appends the data fields of text and std output in the
+// order of `DataKeys`. Map field values are JSON encoded, typed fields are
+// encoded directly.
+func appendTextData(b []byte, data *logData, escapeHTML bool) ([]byte, error) {
+	for _, k := range data.DataKeys {
+		b = append(b, ' ')
+		if "" != data.LabelData {
+			b = append(b, data.LabelData...)
+			b = append(b, '.')
+		}
+		b = append(b, k...)
+		b = append(b, '=')
+		if i := typedIndex(data.Typed, k); 0 <= i {
+			var err error
+			if b, err = data.Typed[i].appendJSON(b, escapeHTML); nil != err {
+				return b, err
+			}
+			continue
+		}
+		b = append(b, escape(data.Data[k], escapeHTML)...)
+	}
+	return b, nil
+}
+
+// quoteASCII escapes s like strconv.QuoteToASCII without adding quotes.
+func quoteASCII(s string) string {
+	q := strconv.QuoteToASCII(s)
+	return q[1 : len(q)-1]
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString appends s as a JSON string, escaped like encoding/json
+// does.
+func appendJSONString(b []byte, s string, escapeHTML bool) []byte {
+	b = append(b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if ' ' <= c && '"' != c && '\\' != c && (!escapeHTML || ('<' != c && '>' != c && '&' != c)) {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
 			default:
-				data.Data[strings.TrimPrefix(k, fieldMap.resolve(LabelData)+".")] = v
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
 			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if utf8.RuneError == r && 1 == size {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if '\u2028' == r || '\u2029' == r {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xf])
+			i += size
+			start = i
+			continue
 		}
+		i += size
 	}
+	b = append(b, s[start:]...)
+	return append(b, '"')
 }
 
 // The Formatter interface is used to implement a custom Formatter. It takes an
@@ -0,0 +1,184 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor returns a unary client interceptor that logs every
+// call through logger.
+func UnaryClientInterceptor(logger log.FieldLogger, opts ...Option) grpc.UnaryClientInterceptor {
+	o := newOptions(DefaultClientCodeToLevel, opts)
+	return func(ctx context.Context, fullMethod string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, callOpts ...grpc.CallOption) error {
+		if o.excluded(fullMethod) {
+			return invoker(ctx, fullMethod, req, reply, cc, callOpts...)
+		}
+
+		start := time.Now()
+		p := &peer.Peer{}
+		err := invoker(ctx, fullMethod, req, reply, cc, withPeer(callOpts, p)...)
+
+		fields := log.Fields{
+			FieldDuration: durationMillis(start),
+			FieldKind:     "unary",
+		}
+		addPeer(fields, p)
+		if size := messageSize(req); size >= 0 {
+			fields[FieldRequestSize] = size
+		}
+		if nil == err {
+			if size := messageSize(reply); size >= 0 {
+				fields[FieldResponseSize] = size
+			}
+		}
+		o.logCall(clientEntry(ctx, logger, fullMethod, cc).WithFields(fields), status.Code(err), err, "finished client unary call")
+		return err
+	}
+}
+
+// StreamClientInterceptor returns a streaming client interceptor that logs
+// every call through logger. A stream is logged once it is finished: when
+// RecvMsg returns io.EOF or an error, after the response of a stream without
+// server streaming is received, or when the call's context is canceled
+// before the stream is drained.
+func StreamClientInterceptor(logger log.FieldLogger, opts ...Option) grpc.StreamClientInterceptor {
+	o := newOptions(DefaultClientCodeToLevel, opts)
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, fullMethod string, streamer grpc.Streamer, callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
+		if o.excluded(fullMethod) {
+			return streamer(ctx, desc, cc, fullMethod, callOpts...)
+		}
+
+		start := time.Now()
+		entry := clientEntry(ctx, logger, fullMethod, cc).WithField(FieldKind, streamKind(desc.ClientStreams, desc.ServerStreams))
+		p := &peer.Peer{}
+		stream, err := streamer(ctx, desc, cc, fullMethod, withPeer(callOpts, p)...)
+		if nil != err {
+			o.logCall(entry.WithField(FieldDuration, durationMillis(start)), status.Code(err), err, "finished client streaming call")
+			return nil, err
+		}
+		wrapped := &clientStream{
+			ClientStream:  stream,
+			entry:         entry,
+			options:       o,
+			start:         start,
+			peer:          p,
+			serverStreams: desc.ServerStreams,
+			done:          make(chan struct{}),
+		}
+		go wrapped.watch(ctx)
+		return wrapped, nil
+	}
+}
+
+// clientEntry builds the entry for a client call.
+func clientEntry(ctx context.Context, logger log.FieldLogger, fullMethod string, cc *grpc.ClientConn) *log.Entry {
+	service, method := splitMethod(fullMethod)
+	fields := log.Fields{
+		FieldService: service,
+		FieldMethod:  method,
+	}
+	if nil != cc {
+		fields[FieldTarget] = cc.Target()
+	}
+	return logger.WithFields(fields).WithContext(ctx)
+}
+
+// withPeer returns a copy of opts that captures the peer in p.
+func withPeer(opts []grpc.CallOption, p *peer.Peer) []grpc.CallOption {
+	return append(opts[:len(opts):len(opts)], grpc.Peer(p))
+}
+
+// addPeer adds the address of the server a call was sent to, gRPC fills in
+// the peer once the call is finished.
+func addPeer(fields log.Fields, p *peer.Peer) {
+	if nil != p.Addr {
+		fields[FieldPeer] = p.Addr.String()
+	}
+}
+
+// clientStream wraps a grpc.ClientStream to count the messages passing
+// through it and log the call once the stream is finished.
+type clientStream struct {
+	grpc.ClientStream
+	entry   *log.Entry
+	options *options
+	start   time.Time
+	peer    *peer.Peer
+	recv    counter
+	sent    counter
+	once    sync.Once
+
+	// serverStreams is false if the server sends a single response, the
+	// stream is finished once it is received.
+	serverStreams bool
+
+	// done is closed when the stream is finished.
+	done chan struct{}
+}
+
+// RecvMsg implements grpc.ClientStream.
+func (s *clientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	switch {
+	case nil == err:
+		s.recv.add(m)
+		if !s.serverStreams {
+			s.finish(nil, true)
+		}
+	case io.EOF == err:
+		s.finish(nil, true)
+	default:
+		s.finish(err, true)
+	}
+	return err
+}
+
+// SendMsg implements grpc.ClientStream.
+func (s *clientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if nil == err {
+		s.sent.add(m)
+	}
+	return err
+}
+
+// watch logs a stream abandoned before it is finished when the call's
+// context is done.
+func (s *clientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		s.finish(status.FromContextError(ctx.Err()).Err(), false)
+	case <-s.done:
+	}
+}
+
+// finish logs the stream once. finished reports whether gRPC has finished
+// the stream, the peer is only filled in then.
+func (s *clientStream) finish(err error, finished bool) {
+	s.once.Do(func() {
+		close(s.done)
+		code := codes.OK
+		if nil != err {
+			code = status.Code(err)
+		}
+		fields := log.Fields{
+			FieldDuration:     durationMillis(s.start),
+			FieldRecvMessages: s.recv.messages.Load(),
+			FieldRecvSize:     s.recv.size.Load(),
+			FieldSentMessages: s.sent.messages.Load(),
+			FieldSentSize:     s.sent.size.Load(),
+		}
+		if finished {
+			addPeer(fields, s.peer)
+		}
+		s.options.logCall(s.entry.WithFields(fields), code, err, "finished client streaming call")
+	})
+}
@@ -0,0 +1,317 @@
+package grpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type logLine struct {
+	Data  map[string]interface{} `json:"data"`
+	Error string                 `json:"error"`
+	Level string                 `json:"level"`
+	Msg   string                 `json:"msg"`
+}
+
+func newLogger() (*log.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = buf
+	logger.Formatter = &log.JSONFormatter{DisableTTY: true}
+	logger.SetLevel(log.DebugLevel)
+	return logger, buf
+}
+
+func lines(t *testing.T, buf *bytes.Buffer) []logLine {
+	result := []logLine{}
+	dec := json.NewDecoder(buf)
+	for {
+		line := logLine{}
+		if err := dec.Decode(&line); io.EOF == err {
+			break
+		} else if nil != err {
+			t.Fatal(err)
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func peerContext() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 1234},
+	})
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := UnaryServerInterceptor(logger)
+	req := wrapperspb.String("hello")
+	resp := wrapperspb.String("hello, world")
+
+	var handlerEntry *log.Entry
+	_, err := interceptor(peerContext(), req, &grpc.UnaryServerInfo{FullMethod: "/pkg.Greeter/SayHello"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerEntry = log.FromContext(ctx)
+			return resp, nil
+		},
+	)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "pkg.Greeter", handlerEntry.Data[FieldService])
+	assert.Equal(t, "SayHello", handlerEntry.Data[FieldMethod])
+	assert.Equal(t, "10.0.0.1:1234", handlerEntry.Data[FieldPeer])
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "info", logs[0].Level)
+		assert.Equal(t, "OK", logs[0].Data[FieldCode])
+		assert.Equal(t, "pkg.Greeter", logs[0].Data[FieldService])
+		assert.Equal(t, "SayHello", logs[0].Data[FieldMethod])
+		assert.Equal(t, "10.0.0.1:1234", logs[0].Data[FieldPeer])
+		assert.Equal(t, float64(proto.Size(req)), logs[0].Data[FieldRequestSize])
+		assert.Equal(t, float64(proto.Size(resp)), logs[0].Data[FieldResponseSize])
+		assert.Contains(t, logs[0].Data, FieldDuration)
+	}
+}
+
+func TestUnaryServerInterceptorLevels(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := UnaryServerInterceptor(logger, WithLevels(map[codes.Code]stdLogger.Level{
+		codes.NotFound: log.DebugLevel,
+	}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/pkg.Greeter/SayHello"}
+
+	for _, code := range []codes.Code{codes.NotFound, codes.InvalidArgument, codes.Internal} {
+		code := code
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(code, "failed")
+		})
+		assert.Equal(t, code, status.Code(err))
+	}
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 3) {
+		assert.Equal(t, "debug", logs[0].Level)
+		assert.Equal(t, "warn", logs[1].Level)
+		assert.Equal(t, "error", logs[2].Level)
+		assert.Equal(t, "Internal", logs[2].Data[FieldCode])
+		assert.Contains(t, logs[2].Error, "failed")
+	}
+}
+
+func TestUnaryServerInterceptorExcludedMethods(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := UnaryServerInterceptor(logger, WithExcludedMethods("/grpc.health.v1.Health/"))
+
+	var handlerEntry *log.Entry
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			handlerEntry = log.FromContext(ctx)
+			return nil, nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "Check", handlerEntry.Data[FieldMethod])
+	assert.Empty(t, buf.String())
+}
+
+type testServerStream struct {
+	ctx  context.Context
+	recv []proto.Message
+}
+
+func (s *testServerStream) SetHeader(metadata.MD) error  { return nil }
+func (s *testServerStream) SendHeader(metadata.MD) error { return nil }
+func (s *testServerStream) SetTrailer(metadata.MD)       {}
+func (s *testServerStream) Context() context.Context     { return s.ctx }
+func (s *testServerStream) SendMsg(m interface{}) error  { return nil }
+func (s *testServerStream) RecvMsg(m interface{}) error {
+	if 0 == len(s.recv) {
+		return io.EOF
+	}
+	proto.Merge(m.(proto.Message), s.recv[0])
+	s.recv = s.recv[1:]
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := StreamServerInterceptor(logger)
+	stream := &testServerStream{
+		ctx:  peerContext(),
+		recv: []proto.Message{wrapperspb.String("a"), wrapperspb.String("b")},
+	}
+
+	err := interceptor(nil, stream, &grpc.StreamServerInfo{FullMethod: "/pkg.Greeter/Chat", IsClientStream: true, IsServerStream: true},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			log.FromContext(stream.Context()).Info("in handler")
+			for {
+				msg := &wrapperspb.StringValue{}
+				if err := stream.RecvMsg(msg); io.EOF == err {
+					break
+				}
+				if err := stream.SendMsg(msg); nil != err {
+					return err
+				}
+			}
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "in handler", logs[0].Msg)
+		assert.Equal(t, "Chat", logs[0].Data[FieldMethod])
+
+		assert.Equal(t, "bidi_stream", logs[1].Data[FieldKind])
+		assert.Equal(t, 2.0, logs[1].Data[FieldRecvMessages])
+		assert.Equal(t, 2.0, logs[1].Data[FieldSentMessages])
+		assert.Equal(t, float64(2*proto.Size(wrapperspb.String("a"))), logs[1].Data[FieldRecvSize])
+	}
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := UnaryClientInterceptor(logger)
+
+	err := interceptor(context.Background(), "/pkg.Greeter/SayHello", wrapperspb.String("hi"), &wrapperspb.StringValue{}, nil,
+		func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			setPeer(opts)
+			return status.Error(codes.Unavailable, "down")
+		},
+	)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "warn", logs[0].Level)
+		assert.Equal(t, "Unavailable", logs[0].Data[FieldCode])
+		assert.Equal(t, "10.0.0.2:443", logs[0].Data[FieldPeer])
+		assert.NotContains(t, logs[0].Data, FieldResponseSize)
+	}
+}
+
+// setPeer fills in the peer like gRPC does once a call is finished.
+func setPeer(opts []grpc.CallOption) {
+	for _, opt := range opts {
+		if p, ok := opt.(grpc.PeerCallOption); ok {
+			*p.PeerAddr = peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}}
+		}
+	}
+}
+
+type testClientStream struct {
+	grpc.ClientStream
+	recv int
+}
+
+func (s *testClientStream) SendMsg(m interface{}) error { return nil }
+func (s *testClientStream) RecvMsg(m interface{}) error {
+	if 0 == s.recv {
+		return io.EOF
+	}
+	s.recv--
+	return nil
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := StreamClientInterceptor(logger)
+
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{ServerStreams: true}, nil, "/pkg.Greeter/List",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			setPeer(opts)
+			return &testClientStream{recv: 3}, nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.SendMsg(wrapperspb.String("list")))
+	for nil == stream.RecvMsg(&wrapperspb.StringValue{}) {
+	}
+	// A second EOF must not log again.
+	assert.Equal(t, io.EOF, stream.RecvMsg(&wrapperspb.StringValue{}))
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "debug", logs[0].Level)
+		assert.Equal(t, "server_stream", logs[0].Data[FieldKind])
+		assert.Equal(t, 3.0, logs[0].Data[FieldRecvMessages])
+		assert.Equal(t, 1.0, logs[0].Data[FieldSentMessages])
+		assert.Equal(t, "10.0.0.2:443", logs[0].Data[FieldPeer])
+	}
+}
+
+func TestStreamClientInterceptorClientStreaming(t *testing.T) {
+	logger, buf := newLogger()
+	interceptor := StreamClientInterceptor(logger)
+
+	stream, err := interceptor(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil, "/pkg.Greeter/Upload",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &testClientStream{recv: 1}, nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.SendMsg(wrapperspb.String("a")))
+	assert.NoError(t, stream.SendMsg(wrapperspb.String("b")))
+	// CloseAndRecv receives the single response once.
+	assert.NoError(t, stream.RecvMsg(&wrapperspb.StringValue{}))
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "client_stream", logs[0].Data[FieldKind])
+		assert.Equal(t, 1.0, logs[0].Data[FieldRecvMessages])
+		assert.Equal(t, 2.0, logs[0].Data[FieldSentMessages])
+	}
+}
+
+// notifyWriter signals every write.
+type notifyWriter struct {
+	bytes.Buffer
+	written chan struct{}
+}
+
+func (w *notifyWriter) Write(p []byte) (int, error) {
+	n, err := w.Buffer.Write(p)
+	w.written <- struct{}{}
+	return n, err
+}
+
+func TestStreamClientInterceptorAbandoned(t *testing.T) {
+	logger, _ := newLogger()
+	out := &notifyWriter{written: make(chan struct{}, 1)}
+	logger.Out = out
+	interceptor := StreamClientInterceptor(logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := interceptor(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/pkg.Greeter/List",
+		func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return &testClientStream{recv: 3}, nil
+		},
+	)
+	assert.NoError(t, err)
+	assert.NoError(t, stream.RecvMsg(&wrapperspb.StringValue{}))
+	cancel()
+	<-out.written
+
+	logs := lines(t, &out.Buffer)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "Canceled", logs[0].Data[FieldCode])
+		assert.Equal(t, 1.0, logs[0].Data[FieldRecvMessages])
+	}
+}
@@ -0,0 +1,161 @@
+package grpc
+
+import (
+	"path"
+	"strings"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/proto"
+)
+
+// Field names used by the interceptors.
+const (
+	FieldCode         = "grpc.code"
+	FieldDuration     = "grpc.duration_ms"
+	FieldKind         = "grpc.kind"
+	FieldMethod       = "grpc.method"
+	FieldPeer         = "peer.address"
+	FieldRecvMessages = "grpc.recv.messages"
+	FieldRecvSize     = "grpc.recv.size"
+	FieldRequestSize  = "grpc.request.size"
+	FieldResponseSize = "grpc.response.size"
+	FieldSentMessages = "grpc.sent.messages"
+	FieldSentSize     = "grpc.sent.size"
+	FieldService      = "grpc.service"
+	FieldTarget       = "grpc.target"
+)
+
+// CodeToLevel maps a gRPC status code to the level the call is logged at.
+type CodeToLevel func(code codes.Code) stdLogger.Level
+
+// DefaultCodeToLevel is the default CodeToLevel used by server interceptors.
+// Successful calls are logged at info, errors caused by the client at warn
+// and server failures at error.
+func DefaultCodeToLevel(code codes.Code) stdLogger.Level {
+	switch code {
+	case codes.OK:
+		return log.InfoLevel
+	case codes.Canceled,
+		codes.InvalidArgument,
+		codes.NotFound,
+		codes.AlreadyExists,
+		codes.PermissionDenied,
+		codes.Unauthenticated,
+		codes.ResourceExhausted,
+		codes.FailedPrecondition,
+		codes.Aborted,
+		codes.OutOfRange:
+		return log.WarnLevel
+	default:
+		return log.ErrorLevel
+	}
+}
+
+// DefaultClientCodeToLevel is the default CodeToLevel used by client
+// interceptors. Successful calls are logged at debug, all failures at warn.
+func DefaultClientCodeToLevel(code codes.Code) stdLogger.Level {
+	if codes.OK == code {
+		return log.DebugLevel
+	}
+	return log.WarnLevel
+}
+
+// Option configures an interceptor.
+type Option func(*options)
+
+type options struct {
+	codeToLevel CodeToLevel
+	exclude     map[string]bool
+}
+
+func newOptions(codeToLevel CodeToLevel, opts []Option) *options {
+	o := &options{
+		codeToLevel: codeToLevel,
+		exclude:     map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithCodeToLevel sets the function used to choose the level for a status
+// code.
+func WithCodeToLevel(fn CodeToLevel) Option {
+	return func(o *options) {
+		o.codeToLevel = fn
+	}
+}
+
+// WithLevels overrides the level for specific status codes. Codes that are
+// not in the map keep the level chosen by the current CodeToLevel function.
+func WithLevels(levels map[codes.Code]stdLogger.Level) Option {
+	return func(o *options) {
+		next := o.codeToLevel
+		o.codeToLevel = func(code codes.Code) stdLogger.Level {
+			if level, ok := levels[code]; ok {
+				return level
+			}
+			return next(code)
+		}
+	}
+}
+
+// WithExcludedMethods disables logging for the given full method names, for
+// example "/grpc.health.v1.Health/Check". A service can be excluded entirely
+// by its name followed by a slash, e.g. "/grpc.health.v1.Health/".
+func WithExcludedMethods(methods ...string) Option {
+	return func(o *options) {
+		for _, method := range methods {
+			o.exclude[method] = true
+		}
+	}
+}
+
+func (o *options) excluded(fullMethod string) bool {
+	if o.exclude[fullMethod] {
+		return true
+	}
+	service, _ := splitMethod(fullMethod)
+	return o.exclude["/"+service+"/"]
+}
+
+// splitMethod splits a full method name of the form "/package.Service/Method"
+// into the service and method names.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.Index(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", path.Base(fullMethod)
+}
+
+// messageSize returns the encoded size of a protobuf message, or -1 if the
+// message is not a protobuf message.
+func messageSize(msg interface{}) int {
+	if m, ok := msg.(proto.Message); ok {
+		return proto.Size(m)
+	}
+	return -1
+}
+
+// logCall writes the log entry for a finished call. Panic and fatal levels
+// are logged as errors, an interceptor never panics or exits the process.
+func (o *options) logCall(entry *log.Entry, code codes.Code, err error, msg string) {
+	entry = entry.WithField(FieldCode, code.String())
+	if nil != err {
+		entry = entry.WithError(err)
+	}
+	switch o.codeToLevel(code) {
+	case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+		entry.Error(msg)
+	case log.WarnLevel:
+		entry.Warn(msg)
+	case log.InfoLevel:
+		entry.Info(msg)
+	default:
+		entry.Debug(msg)
+	}
+}
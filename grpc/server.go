@@ -0,0 +1,151 @@
+package grpc
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor returns a unary server interceptor that logs every
+// call through logger. The handler's context carries a request-scoped entry
+// with the service, method and peer fields already set, retrieve it with
+// `log.FromContext(ctx)`.
+func UnaryServerInterceptor(logger log.FieldLogger, opts ...Option) grpc.UnaryServerInterceptor {
+	o := newOptions(DefaultCodeToLevel, opts)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		entry := serverEntry(ctx, logger, info.FullMethod)
+		ctx = log.NewContext(ctx, entry)
+		if o.excluded(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		fields := log.Fields{
+			FieldDuration: durationMillis(start),
+			FieldKind:     "unary",
+		}
+		if size := messageSize(req); size >= 0 {
+			fields[FieldRequestSize] = size
+		}
+		if nil == err {
+			if size := messageSize(resp); size >= 0 {
+				fields[FieldResponseSize] = size
+			}
+		}
+		o.logCall(entry.WithFields(fields), status.Code(err), err, "finished unary call")
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a streaming server interceptor that logs
+// every call through logger. The stream's context carries a request-scoped
+// entry with the service, method and peer fields already set, retrieve it
+// with `log.FromContext(stream.Context())`.
+func StreamServerInterceptor(logger log.FieldLogger, opts ...Option) grpc.StreamServerInterceptor {
+	o := newOptions(DefaultCodeToLevel, opts)
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		entry := serverEntry(stream.Context(), logger, info.FullMethod)
+		wrapped := &serverStream{
+			ServerStream: stream,
+			ctx:          log.NewContext(stream.Context(), entry),
+		}
+		if o.excluded(info.FullMethod) {
+			return handler(srv, wrapped)
+		}
+
+		start := time.Now()
+		err := handler(srv, wrapped)
+
+		o.logCall(entry.WithFields(log.Fields{
+			FieldDuration:     durationMillis(start),
+			FieldKind:         streamKind(info.IsClientStream, info.IsServerStream),
+			FieldRecvMessages: wrapped.recv.messages.Load(),
+			FieldRecvSize:     wrapped.recv.size.Load(),
+			FieldSentMessages: wrapped.sent.messages.Load(),
+			FieldSentSize:     wrapped.sent.size.Load(),
+		}), status.Code(err), err, "finished streaming call")
+		return err
+	}
+}
+
+// serverEntry builds the request-scoped entry for a server call.
+func serverEntry(ctx context.Context, logger log.FieldLogger, fullMethod string) *log.Entry {
+	service, method := splitMethod(fullMethod)
+	fields := log.Fields{
+		FieldService: service,
+		FieldMethod:  method,
+	}
+	if p, ok := peer.FromContext(ctx); ok && nil != p.Addr {
+		fields[FieldPeer] = p.Addr.String()
+	}
+	return logger.WithFields(fields).WithContext(ctx)
+}
+
+// serverStream wraps a grpc.ServerStream to replace its context and count
+// the messages passing through it.
+type serverStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv counter
+	sent counter
+}
+
+// Context returns the stream context carrying the request-scoped entry.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// RecvMsg implements grpc.ServerStream.
+func (s *serverStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if nil == err {
+		s.recv.add(m)
+	}
+	return err
+}
+
+// SendMsg implements grpc.ServerStream.
+func (s *serverStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if nil == err {
+		s.sent.add(m)
+	}
+	return err
+}
+
+// counter tracks the number and total encoded size of stream messages. It is
+// safe for concurrent use, messages may be sent while others are received.
+type counter struct {
+	messages atomic.Int64
+	size     atomic.Int64
+}
+
+func (c *counter) add(m interface{}) {
+	c.messages.Add(1)
+	if size := messageSize(m); size > 0 {
+		c.size.Add(int64(size))
+	}
+}
+
+func streamKind(client, server bool) string {
+	switch {
+	case client && server:
+		return "bidi_stream"
+	case client:
+		return "client_stream"
+	case server:
+		return "server_stream"
+	}
+	return "stream"
+}
+
+func durationMillis(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
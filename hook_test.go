@@ -1,8 +1,11 @@
 package log
 
 import (
+	"bytes"
+	"errors"
 	"sync"
 	"testing"
+	"time"
 
 	stdLogger "github.com/bdlm/std/v2/logger"
 	"github.com/stretchr/testify/assert"
@@ -143,3 +146,318 @@ func TestAddHookRace(t *testing.T) {
 		// actually assert on the hook
 	})
 }
+
+type FailingHook struct {
+	Err error
+}
+
+func (hook *FailingHook) Fire(entry *Entry) error {
+	return hook.Err
+}
+
+func (hook *FailingHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestFailingHookDoesNotStopOtherHooks(t *testing.T) {
+	failing := &FailingHook{Err: errors.New("failed")}
+	hook := new(TestHook)
+
+	var hookErr error
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.SetHookErrorHandler(func(entry *Entry, err error) {
+			hookErr = err
+		})
+		log.Hooks.Add(failing)
+		log.Hooks.Add(&panickyHook{})
+		log.Hooks.Add(hook)
+		log.Info(badMessage)
+	}, func(data logData) {
+		assert.Equal(t, badMessage, data.Message)
+	})
+
+	assert.True(t, hook.Fired)
+	errs, ok := hookErr.(HookErrors)
+	if assert.True(t, ok) && assert.Len(t, errs, 2) {
+		assert.Equal(t, failing, errs[0].Hook)
+		assert.Equal(t, failing.Err, errs[0].Err)
+		assert.Nil(t, errs[0].Panic)
+		assert.Equal(t, panicMessage, errs[1].Panic)
+	}
+	assert.Contains(t, hookErr.Error(), "failed")
+	assert.Contains(t, hookErr.Error(), panicMessage)
+}
+
+func TestHooksFiredOutsideOutputLock(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.Level = DebugLevel
+
+	// A hook that logs would deadlock if hooks were fired under the lock.
+	logger.AddHook(&loggingHook{logger: logger})
+	logger.Info("test")
+}
+
+type loggingHook struct {
+	logger *Logger
+}
+
+func (hook *loggingHook) Fire(entry *Entry) error {
+	if InfoLevel == entry.Level {
+		hook.logger.Warn("from hook")
+	}
+	return nil
+}
+
+func (hook *loggingHook) Levels() []stdLogger.Level {
+	return []stdLogger.Level{InfoLevel}
+}
+
+// blockingHook records entries, blocking until release is closed.
+type blockingHook struct {
+	mu      sync.Mutex
+	release chan struct{}
+	entries []*Entry
+}
+
+func (hook *blockingHook) Fire(entry *Entry) error {
+	<-hook.release
+	hook.mu.Lock()
+	hook.entries = append(hook.entries, entry)
+	hook.mu.Unlock()
+	return nil
+}
+
+func (hook *blockingHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestAsyncHook(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+
+	slow := &blockingHook{release: make(chan struct{})}
+	hook := NewAsyncHook(slow, AsyncHookOptions{Workers: 2, QueueSize: 4})
+	logger.AddHook(hook)
+
+	// The logging call does not wait for the hook.
+	logger.WithField("n", 1).Info("first")
+	assert.Contains(t, buffer.String(), "first")
+
+	close(slow.release)
+	assert.Nil(t, hook.Close())
+	if assert.Len(t, slow.entries, 1) {
+		assert.Equal(t, "first", slow.entries[0].Message)
+		assert.Equal(t, 1, slow.entries[0].Data["n"])
+		assert.Nil(t, slow.entries[0].Buffer)
+	}
+
+	// Once closed, the hook is fired synchronously.
+	logger.Info("second")
+	assert.Len(t, slow.entries, 2)
+}
+
+func TestAsyncHookQueueFull(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	errs := make(chan error, 10)
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		errs <- err
+	})
+
+	slow := &blockingHook{release: make(chan struct{})}
+	hook := NewAsyncHook(slow, AsyncHookOptions{QueueSize: 1})
+	logger.AddHook(hook)
+
+	// One entry is being fired, one is queued, the rest are dropped.
+	for i := 0; i < 4; i++ {
+		logger.Info("test")
+	}
+	close(slow.release)
+	hook.Close()
+
+	dropped := 0
+	for 0 < len(errs) {
+		err := <-errs
+		assert.Equal(t, ErrHookQueueFull, err.(HookErrors)[0].Err)
+		dropped++
+	}
+	assert.True(t, dropped >= 2, "dropped %d", dropped)
+	assert.Len(t, slow.entries, 4-dropped)
+}
+
+func TestAsyncHookTimeout(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	errs := make(chan error, 1)
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		errs <- err
+	})
+
+	slow := &blockingHook{release: make(chan struct{})}
+	defer close(slow.release)
+	hook := NewAsyncHook(slow, AsyncHookOptions{Timeout: 10 * time.Millisecond})
+	logger.AddHook(hook)
+	logger.Info("test")
+
+	select {
+	case err := <-errs:
+		hookErr, ok := err.(*HookError)
+		if assert.True(t, ok) {
+			assert.Equal(t, slow, hookErr.Hook)
+			assert.Equal(t, ErrHookTimeout, hookErr.Err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout was not reported")
+	}
+	hook.Close()
+}
+
+func TestAsyncHookTimeoutBounded(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	errs := make(chan error, 10)
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		errs <- err
+	})
+
+	slow := &blockingHook{release: make(chan struct{})}
+	hook := NewAsyncHook(slow, AsyncHookOptions{Timeout: 10 * time.Millisecond})
+	logger.AddHook(hook)
+	for i := 0; i < 3; i++ {
+		logger.Info("test")
+	}
+
+	// The first call times out and keeps running, the others are dropped
+	// instead of starting more calls.
+	reported := []error{}
+	for len(reported) < 3 {
+		select {
+		case err := <-errs:
+			reported = append(reported, err.(*HookError).Err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeouts were not reported")
+		}
+	}
+	assert.Equal(t, []error{ErrHookTimeout, ErrHookBusy, ErrHookBusy}, reported)
+	assert.Equal(t, uint64(2), hook.Dropped())
+
+	// Close doesn't wait for the timed out call, wait for its token.
+	close(slow.release)
+	hook.Close()
+	hook.calls <- struct{}{}
+	slow.mu.Lock()
+	defer slow.mu.Unlock()
+	assert.Len(t, slow.entries, 1)
+}
+
+// deadlineHook records whether the entry's context has a deadline.
+type deadlineHook struct {
+	deadline chan bool
+}
+
+func (hook *deadlineHook) Fire(entry *Entry) error {
+	_, ok := entry.Context.Deadline()
+	hook.deadline <- ok
+	return nil
+}
+
+func (hook *deadlineHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestAsyncHookTimeoutContext(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	deadline := &deadlineHook{deadline: make(chan bool, 1)}
+	hook := NewAsyncHook(deadline, AsyncHookOptions{Timeout: time.Second})
+	logger.AddHook(hook)
+	logger.Info("test")
+	hook.Close()
+	assert.True(t, <-deadline.deadline)
+}
+
+// orderHook records the order hooks are fired in.
+type orderHook struct {
+	name  string
+	order *[]string
+}
+
+func (hook *orderHook) Fire(entry *Entry) error {
+	*hook.order = append(*hook.order, hook.name)
+	return nil
+}
+
+func (hook *orderHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestHookPriority(t *testing.T) {
+	order := []string{}
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+
+	logger.AddHook(&orderHook{name: "plain", order: &order})
+	logger.AddHookWithPriority(&orderHook{name: "low", order: &order}, -10)
+	logger.AddHookWithPriority(&orderHook{name: "high", order: &order}, 10)
+	logger.AddHookWithPriority(&orderHook{name: "high2", order: &order}, 10)
+	logger.AddHookWithPriority(&orderHook{name: "zero", order: &order}, 0)
+
+	logger.Info("test")
+	assert.Equal(t, []string{"high", "high2", "plain", "zero", "low"}, order)
+}
+
+func TestHookPriorityAdd(t *testing.T) {
+	order := []string{}
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+
+	// Hooks added with Add have priority 0 and go before negative ones.
+	logger.AddHookWithPriority(&orderHook{name: "low", order: &order}, -5)
+	logger.AddHook(&orderHook{name: "plain", order: &order})
+	logger.AddHookWithPriority(&orderHook{name: "lower", order: &order}, -10)
+	logger.Hooks.Add(&orderHook{name: "plain2", order: &order})
+	logger.AddHookWithPriority(&orderHook{name: "high", order: &order}, 1)
+
+	logger.Info("test")
+	assert.Equal(t, []string{"high", "plain", "plain2", "low", "lower"}, order)
+}
+
+func TestRemoveHook(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	kept := new(TestHook)
+	removed := new(TestHook)
+	logger.AddHook(kept)
+	handle := logger.AddHookWithPriority(removed, 0)
+
+	logger.RemoveHook(handle)
+	logger.Info("test")
+	assert.True(t, kept.Fired)
+	assert.False(t, removed.Fired)
+	for _, hooks := range logger.Hooks {
+		for _, hook := range hooks {
+			assert.NotEqual(t, Hook(handle), hook)
+		}
+	}
+
+	// Removing twice is a no-op.
+	logger.RemoveHook(handle)
+}
+
+func TestHookHandleErrorsReportWrappedHook(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	failing := &FailingHook{Err: errors.New("failed")}
+	logger.AddHookWithPriority(failing, 1)
+
+	var hookErr error
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		hookErr = err
+	})
+	logger.Info("test")
+	if errs, ok := hookErr.(HookErrors); assert.True(t, ok) {
+		assert.Equal(t, failing, errs[0].Hook)
+	}
+}
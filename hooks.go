@@ -1,14 +1,24 @@
 package log
 
 import (
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/bdlm/std/v2/logger"
 )
 
 // Hook defines a hook to be fired when logging on the logging levels returned
-// from `Levels()` on your implementation of the interface. Note that this is
-// not fired in a goroutine or a channel with workers, you should handle such
-// functionality yourself if your call is non-blocking and you don't wish for
-// the logging calls for levels returned from `Levels()` to block.
+// from `Levels()` on your implementation of the interface. Hooks are fired
+// synchronously by the logging goroutine and may be fired concurrently by
+// several goroutines, wrap a hook with `NewAsyncHook` to run it on a bounded
+// pool of background workers instead.
+//
+// Every hook registered for the level is fired even if an earlier one fails.
+// Errors and panics are reported to the logger's `HookErrorHandler`.
+//
+// The context passed to `WithContext`, if any, is available to `Fire` as
+// `entry.Context`.
 type Hook interface {
 	Levels() []logger.Level
 	Fire(*Entry) error
@@ -19,20 +29,206 @@ type LevelHooks map[logger.Level][]Hook
 
 // Add a hook to an instance of logger. This is called with
 // `log.Hooks.Add(new(MyHook))` where `MyHook` implements the `Hook` interface.
+// The hook has priority 0, see `AddWithPriority`.
 func (hooks LevelHooks) Add(hook Hook) {
+	hooks.insert(hook, 0)
+}
+
+// AddWithPriority adds a hook that is fired before hooks with a lower
+// priority. Hooks added with `Add` have priority 0, hooks with the same
+// priority are fired in the order they were added. The returned handle
+// removes the hook, see `Remove`.
+func (hooks LevelHooks) AddWithPriority(hook Hook, priority int) *HookHandle {
+	handle := &HookHandle{Hook: hook, Priority: priority}
+	hooks.insert(handle, priority)
+	return handle
+}
+
+// insert adds hook after the hooks with the same or a higher priority.
+func (hooks LevelHooks) insert(hook Hook, priority int) {
 	for _, level := range hook.Levels() {
-		hooks[level] = append(hooks[level], hook)
+		current := hooks[level]
+		i := 0
+		for i < len(current) && hookPriority(current[i]) >= priority {
+			i++
+		}
+		// Copy, the current list may be in use by a logging call.
+		updated := make([]Hook, 0, len(current)+1)
+		updated = append(updated, current[:i]...)
+		updated = append(updated, hook)
+		hooks[level] = append(updated, current[i:]...)
+	}
+}
+
+// Remove removes a hook added with `AddWithPriority`.
+func (hooks LevelHooks) Remove(handle *HookHandle) {
+	for level, current := range hooks {
+		updated := make([]Hook, 0, len(current))
+		for _, hook := range current {
+			if hook != Hook(handle) {
+				updated = append(updated, hook)
+			}
+		}
+		if len(updated) < len(current) {
+			hooks[level] = updated
+		}
 	}
 }
 
+// HookHandle identifies a hook or processor added with a priority. It is
+// used to remove it again, e.g. at the end of a test.
+type HookHandle struct {
+	// Hook is the registered hook, nil for processors.
+	Hook Hook
+	// Processor is the registered processor, nil for hooks.
+	Processor Processor
+	// Priority is the priority the hook or processor was added with.
+	Priority int
+}
+
+// Levels implements Hook.
+func (handle *HookHandle) Levels() []logger.Level {
+	if nil == handle.Hook {
+		return nil
+	}
+	return handle.Hook.Levels()
+}
+
+// Fire implements Hook.
+func (handle *HookHandle) Fire(entry *Entry) error {
+	if nil == handle.Hook {
+		return nil
+	}
+	return handle.Hook.Fire(entry)
+}
+
+func hookPriority(hook Hook) int {
+	if handle, ok := hook.(*HookHandle); ok {
+		return handle.Priority
+	}
+	return 0
+}
+
+// AddHookWithPriority adds a hook to the logger, see
+// `LevelHooks.AddWithPriority`.
+func (logger *Logger) AddHookWithPriority(hook Hook, priority int) *HookHandle {
+	logger = logger.root()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return logger.Hooks.AddWithPriority(hook, priority)
+}
+
+// RemoveHook removes a hook or processor added with `AddHookWithPriority` or
+// `AddProcessor`.
+func (logger *Logger) RemoveHook(handle *HookHandle) {
+	logger = logger.root()
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	logger.Hooks.Remove(handle)
+	logger.removeProcessor(handle)
+}
+
 // Fire all the hooks for the passed level. Used by `entry.log` to fire
-// appropriate hooks for a log entry.
+// appropriate hooks for a log entry. Every hook is fired, panics are
+// recovered and all failures are returned as `HookErrors`.
 func (hooks LevelHooks) Fire(level logger.Level, entry *Entry) error {
-	for _, hook := range hooks[level] {
-		if err := hook.Fire(entry); err != nil {
-			return err
+	return fireHooks(hooks[level], entry)
+}
+
+// HookError is a failure of a single hook or processor.
+type HookError struct {
+	// Hook is the hook that failed, nil for processors.
+	Hook Hook
+	// Err is the error returned by the hook, or describes the panic.
+	Err error
+	// Panic is the value the hook panicked with, nil if it returned an
+	// error.
+	Panic interface{}
+}
+
+// Error implements error.
+func (err *HookError) Error() string {
+	if nil == err.Hook {
+		return err.Err.Error()
+	}
+	return fmt.Sprintf("hook %T: %v", err.Hook, err.Err)
+}
+
+// Unwrap returns the error returned by the hook.
+func (err *HookError) Unwrap() error {
+	return err.Err
+}
+
+// HookErrors holds the failures of all hooks fired for an entry.
+type HookErrors []*HookError
+
+// Error implements error.
+func (errs HookErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the individual hook errors.
+func (errs HookErrors) Unwrap() []error {
+	result := make([]error, len(errs))
+	for i, err := range errs {
+		result[i] = err
+	}
+	return result
+}
+
+// HookErrorHandler is called with the entry and the error when hooks fail.
+// The error is `HookErrors` for hooks fired by the logging call, and a
+// `*HookError` for hooks run by `AsyncHook` workers.
+type HookErrorHandler func(entry *Entry, err error)
+
+// SetHookErrorHandler sets the function hook failures are reported to. By
+// default they are printed to `os.Stderr`. Pass nil to restore the default.
+func (logger *Logger) SetHookErrorHandler(handler HookErrorHandler) {
+	logger = logger.root()
+	logger.hookErrorHandler.Store(handler)
+}
+
+// handleHookError reports a hook failure to the logger's handler.
+func (logger *Logger) handleHookError(entry *Entry, err error) {
+	if nil != logger {
+		if handler, _ := logger.root().hookErrorHandler.Load().(HookErrorHandler); nil != handler {
+			handler(entry, err)
+			return
 		}
 	}
+	fmt.Fprintf(os.Stderr, "Failed to fire hook: %v\n", err)
+}
 
+// fireHooks fires every hook and aggregates their failures.
+func fireHooks(hooks []Hook, entry *Entry) error {
+	var errs HookErrors
+	for _, hook := range hooks {
+		if err := fireHook(hook, entry); nil != err {
+			errs = append(errs, err)
+		}
+	}
+	if 0 == len(errs) {
+		return nil
+	}
+	return errs
+}
+
+// fireHook fires a single hook, recovering from panics.
+func fireHook(hook Hook, entry *Entry) (hookErr *HookError) {
+	if handle, ok := hook.(*HookHandle); ok && nil != handle.Hook {
+		hook = handle.Hook
+	}
+	defer func() {
+		if r := recover(); nil != r {
+			hookErr = &HookError{Hook: hook, Err: fmt.Errorf("panic: %v", r), Panic: r}
+		}
+	}()
+	if err := hook.Fire(entry); nil != err {
+		return &HookError{Hook: hook, Err: err}
+	}
 	return nil
 }
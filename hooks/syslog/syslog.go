@@ -34,25 +34,32 @@ func (hook *Hook) Fire(entry *log.Entry) error {
 		return err
 	}
 
-	switch entry.Level {
-	case log.PanicLevel:
-		return hook.Writer.Crit(line)
-	case log.FatalLevel:
+	severity := log.SyslogInfo
+	if info, ok := log.LookupLevel(entry.Level); ok {
+		severity = info.Syslog
+	}
+	switch severity {
+	case log.SyslogEmergency:
+		return hook.Writer.Emerg(line)
+	case log.SyslogAlert:
+		return hook.Writer.Alert(line)
+	case log.SyslogCritical:
 		return hook.Writer.Crit(line)
-	case log.ErrorLevel:
+	case log.SyslogError:
 		return hook.Writer.Err(line)
-	case log.WarnLevel:
+	case log.SyslogWarning:
 		return hook.Writer.Warning(line)
-	case log.InfoLevel:
-		return hook.Writer.Info(line)
-	case log.DebugLevel:
+	case log.SyslogNotice:
+		return hook.Writer.Notice(line)
+	case log.SyslogDebug:
 		return hook.Writer.Debug(line)
 	default:
-		return nil
+		return hook.Writer.Info(line)
 	}
 }
 
-// Levels returns all available log levels.
+// Levels returns all registered log levels, including custom levels
+// registered before the hook is added.
 func (hook *Hook) Levels() []stdLogger.Level {
-	return log.AllLevels
+	return log.RegisteredLevels()
 }
@@ -0,0 +1,171 @@
+// Package test provides a hook that records log entries so tests can assert
+// on what was logged without parsing formatted output.
+package test
+
+import (
+	"io/ioutil"
+	"reflect"
+	"sync"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// Hook is a hook that records a copy of every entry fired at it. It is safe
+// for concurrent use.
+type Hook struct {
+	mu      sync.RWMutex
+	entries []*log.Entry
+}
+
+// NewGlobal installs a recording hook on the standard logger.
+func NewGlobal() *Hook {
+	hook := new(Hook)
+	log.AddHook(hook)
+	return hook
+}
+
+// NewLocal installs a recording hook on a logger.
+func NewLocal(logger *log.Logger) *Hook {
+	hook := new(Hook)
+	logger.AddHook(hook)
+	return hook
+}
+
+// NewNullLogger returns a logger that discards its output and records every
+// entry in the returned hook. The logger's level is `log.DebugLevel` and
+// Fatal calls do not exit, so Fatal entries can be captured too.
+func NewNullLogger() (*log.Logger, *Hook) {
+	logger := log.New()
+	// Not ioutil.Discard, entries logged to it are not built at all.
+	logger.Out = discard{}
+	logger.Level = log.DebugLevel
+	logger.ExitFunc = func(int) {}
+	return logger, NewLocal(logger)
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return ioutil.Discard.Write(p)
+}
+
+// Levels implements log.Hook. It returns every registered level, including
+// custom levels registered before the hook is added.
+func (hook *Hook) Levels() []stdLogger.Level {
+	return log.RegisteredLevels()
+}
+
+// Fire implements log.Hook.
+func (hook *Hook) Fire(entry *log.Entry) error {
+	clone := *entry
+	clone.Buffer = nil
+	clone.Data = make(log.Fields, len(entry.Data))
+	for k, v := range entry.Data {
+		clone.Data[k] = v
+	}
+
+	hook.mu.Lock()
+	hook.entries = append(hook.entries, &clone)
+	hook.mu.Unlock()
+	return nil
+}
+
+// LastEntry returns the last recorded entry, or nil.
+func (hook *Hook) LastEntry() *log.Entry {
+	hook.mu.RLock()
+	defer hook.mu.RUnlock()
+	if 0 == len(hook.entries) {
+		return nil
+	}
+	return hook.entries[len(hook.entries)-1]
+}
+
+// AllEntries returns all recorded entries in the order they were logged.
+func (hook *Hook) AllEntries() []*log.Entry {
+	hook.mu.RLock()
+	defer hook.mu.RUnlock()
+	entries := make([]*log.Entry, len(hook.entries))
+	copy(entries, hook.entries)
+	return entries
+}
+
+// Len returns the number of recorded entries.
+func (hook *Hook) Len() int {
+	hook.mu.RLock()
+	defer hook.mu.RUnlock()
+	return len(hook.entries)
+}
+
+// Reset removes all recorded entries.
+func (hook *Hook) Reset() {
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	hook.entries = nil
+}
+
+// Find returns the recorded entries that match all matchers.
+//
+//	hook.Find(test.Level(log.ErrorLevel), test.Field("user", "alice"))
+func (hook *Hook) Find(matchers ...Matcher) []*log.Entry {
+	found := []*log.Entry{}
+	for _, entry := range hook.AllEntries() {
+		if matchAll(entry, matchers) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Contains reports whether any recorded entry matches all matchers.
+func (hook *Hook) Contains(matchers ...Matcher) bool {
+	for _, entry := range hook.AllEntries() {
+		if matchAll(entry, matchers) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAll(entry *log.Entry, matchers []Matcher) bool {
+	for _, match := range matchers {
+		if !match(entry) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matcher reports whether an entry matches a condition.
+type Matcher func(entry *log.Entry) bool
+
+// Level matches entries logged at a level.
+func Level(level stdLogger.Level) Matcher {
+	return func(entry *log.Entry) bool {
+		return entry.Level == level
+	}
+}
+
+// Message matches entries with a message.
+func Message(msg string) Matcher {
+	return func(entry *log.Entry) bool {
+		return entry.Message == msg
+	}
+}
+
+// Field matches entries with a field set to a value. Map and typed fields
+// are both checked.
+func Field(key string, value interface{}) Matcher {
+	return func(entry *log.Entry) bool {
+		v, ok := entry.AllData()[key]
+		return ok && reflect.DeepEqual(v, value)
+	}
+}
+
+// HasField matches entries with a field, regardless of its value.
+func HasField(key string) Matcher {
+	return func(entry *log.Entry) bool {
+		_, ok := entry.AllData()[key]
+		return ok
+	}
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/bdlm/log/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNullLogger(t *testing.T) {
+	logger, hook := NewNullLogger()
+	assert.Nil(t, hook.LastEntry())
+	assert.Equal(t, 0, hook.Len())
+
+	logger.WithField("user", "alice").Debug("debug")
+	logger.With(log.Int("attempt", 2)).Error("failed")
+
+	assert.Equal(t, 2, hook.Len())
+	if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+		assert.Equal(t, log.ErrorLevel, entry.Level)
+		assert.Equal(t, "failed", entry.Message)
+	}
+	entries := hook.AllEntries()
+	assert.Equal(t, "debug", entries[0].Message)
+	assert.Equal(t, "alice", entries[0].Data["user"])
+
+	hook.Reset()
+	assert.Nil(t, hook.LastEntry())
+	assert.Empty(t, hook.AllEntries())
+}
+
+func TestMatchers(t *testing.T) {
+	logger, hook := NewNullLogger()
+	logger.WithField("user", "alice").Info("login")
+	logger.WithField("user", "bob").Info("login")
+	logger.WithField("user", "bob").WithError(errors.New("denied")).Warn("login failed")
+	logger.With(log.Int("attempt", 3)).Warn("retry")
+
+	assert.Len(t, hook.Find(Level(log.InfoLevel)), 2)
+	assert.Len(t, hook.Find(Message("login"), Field("user", "bob")), 1)
+	assert.Len(t, hook.Find(Field("attempt", int64(3))), 1)
+	assert.Len(t, hook.Find(HasField("user")), 3)
+	assert.Len(t, hook.Find(), 4)
+	assert.Empty(t, hook.Find(Level(log.ErrorLevel)))
+	assert.True(t, hook.Contains(Level(log.WarnLevel), Field("user", "bob")))
+	assert.False(t, hook.Contains(Message("logout")))
+}
+
+func TestEntriesAreCopies(t *testing.T) {
+	logger, hook := NewNullLogger()
+	entry := logger.WithField("n", 1)
+	entry.Info("first")
+	entry.Data["n"] = 2
+	entry.Info("second")
+
+	entries := hook.AllEntries()
+	assert.Equal(t, 1, entries[0].Data["n"])
+	assert.Equal(t, 2, entries[1].Data["n"])
+}
+
+func TestFatalDoesNotExit(t *testing.T) {
+	logger, hook := NewNullLogger()
+	code := -1
+	logger.ExitFunc = func(c int) {
+		code = c
+	}
+
+	logger.Fatal("fatal")
+	assert.Equal(t, 1, code)
+	if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+		assert.Equal(t, log.FatalLevel, entry.Level)
+		assert.Equal(t, "fatal", entry.Message)
+	}
+
+	code = -1
+	logger.WithField("k", "v").Fatalf("fatal %d", 2)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "fatal 2", hook.LastEntry().Message)
+	assert.Equal(t, 2, hook.Len())
+}
+
+func TestPanicIsCaptured(t *testing.T) {
+	logger, hook := NewNullLogger()
+	assert.Panics(t, func() {
+		logger.Panic("panic")
+	})
+	assert.Equal(t, log.PanicLevel, hook.LastEntry().Level)
+}
+
+func TestNewLocal(t *testing.T) {
+	logger := log.New()
+	logger.Out = discard{}
+	hook := NewLocal(logger)
+	logger.Info("test")
+	assert.True(t, hook.Contains(Message("test")))
+}
+
+func TestConcurrentCapture(t *testing.T) {
+	logger, hook := NewNullLogger()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				logger.Info("test")
+				hook.LastEntry()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1000, hook.Len())
+}
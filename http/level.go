@@ -0,0 +1,186 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// maxLevelBody limits the size of level change requests.
+const maxLevelBody = 4096
+
+// LevelRequest is the JSON body accepted by `LevelHandler`. TTL is a
+// duration string such as "10m", if set the previous level is restored once
+// it has elapsed.
+type LevelRequest struct {
+	Level string `json:"level"`
+	TTL   string `json:"ttl,omitempty"`
+}
+
+// LevelResponse is the JSON body returned by `LevelHandler`.
+type LevelResponse struct {
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// LevelHandler returns a handler that reports the logger's level on GET and
+// changes it on PUT or POST.
+//
+// The new level is read from a JSON body, `{"level": "debug", "ttl": "10m"}`,
+// or from a plain-text body containing just the level name. The TTL can also
+// be passed as the "ttl" query parameter. When a TTL is set the level in
+// effect before the change is restored once it elapses. Changing the level
+// again before then replaces the TTL, a change without a TTL is permanent.
+//
+// Responses are JSON, or plain text containing the level name if the request
+// accepts text/plain but not application/json.
+func LevelHandler(logger *log.Logger) http.Handler {
+	return &levelHandler{logger: logger}
+}
+
+type levelHandler struct {
+	logger *log.Logger
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	revertTo stdLogger.Level
+	revertAt time.Time
+}
+
+// ServeHTTP implements http.Handler.
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		h.respond(w, r, http.StatusOK, "")
+	case http.MethodPut, http.MethodPost:
+		level, ttl, err := parseLevelRequest(r)
+		if nil != err {
+			h.respond(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.set(level, ttl)
+		h.respond(w, r, http.StatusOK, "")
+	default:
+		w.Header().Set("Allow", "GET, HEAD, POST, PUT")
+		h.respond(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("method %s not allowed", r.Method))
+	}
+}
+
+// set changes the level and schedules or cancels the revert.
+func (h *levelHandler) set(level stdLogger.Level, ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pending := h.stop()
+	if ttl > 0 {
+		// Keep the original level if a revert was already pending.
+		if !pending {
+			h.revertTo = h.logger.GetLevel()
+		}
+		h.revertAt = time.Now().Add(ttl)
+		var timer *time.Timer
+		timer = time.AfterFunc(ttl, func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			if h.timer != timer {
+				return
+			}
+			h.logger.SetLevel(h.revertTo)
+			h.timer = nil
+		})
+		h.timer = timer
+	}
+	h.logger.SetLevel(level)
+}
+
+// stop cancels a pending revert and reports whether there was one. It must
+// be called with the lock held.
+func (h *levelHandler) stop() bool {
+	if nil == h.timer {
+		return false
+	}
+	h.timer.Stop()
+	h.timer = nil
+	return true
+}
+
+func (h *levelHandler) respond(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	h.mu.Lock()
+	resp := LevelResponse{
+		Level: log.LevelString(h.logger.GetLevel()),
+		Error: msg,
+	}
+	if nil != h.timer {
+		revertAt := h.revertAt
+		resp.RevertTo = log.LevelString(h.revertTo)
+		resp.RevertAt = &revertAt
+	}
+	h.mu.Unlock()
+
+	if acceptsPlainText(r) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(status)
+		if "" != msg {
+			fmt.Fprintln(w, msg)
+			return
+		}
+		fmt.Fprintln(w, resp.Level)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// parseLevelRequest reads the level and TTL from a JSON or plain-text body.
+func parseLevelRequest(r *http.Request) (stdLogger.Level, time.Duration, error) {
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxLevelBody))
+	if nil != err {
+		return 0, 0, fmt.Errorf("failed to read request body: %v", err)
+	}
+
+	req := LevelRequest{TTL: r.URL.Query().Get("ttl")}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	trimmed := strings.TrimSpace(string(body))
+	if "application/json" == mediaType || strings.HasPrefix(trimmed, "{") {
+		if err := json.Unmarshal(body, &req); nil != err {
+			return 0, 0, fmt.Errorf("invalid JSON body: %v", err)
+		}
+	} else {
+		req.Level = trimmed
+	}
+	if "" == req.Level {
+		return 0, 0, fmt.Errorf("missing level")
+	}
+
+	level, err := log.ParseLevel(req.Level)
+	if nil != err {
+		return 0, 0, err
+	}
+	var ttl time.Duration
+	if "" != req.TTL {
+		if ttl, err = time.ParseDuration(req.TTL); nil != err {
+			return 0, 0, fmt.Errorf("invalid ttl: %v", err)
+		}
+		if ttl < 0 {
+			return 0, 0, fmt.Errorf("invalid ttl: %q is negative", req.TTL)
+		}
+	}
+	return level, ttl, nil
+}
+
+// acceptsPlainText reports whether the response should be plain text.
+func acceptsPlainText(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	return strings.Contains(accept, "text/plain") && !strings.Contains(accept, "application/json")
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveLevel(h http.Handler, method, target, contentType, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	if "" != contentType {
+		req.Header.Set("Content-Type", contentType)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeLevel(t *testing.T, rec *httptest.ResponseRecorder) LevelResponse {
+	resp := LevelResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); nil != err {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestLevelHandlerGet(t *testing.T) {
+	logger, _ := newLogger()
+	h := LevelHandler(logger)
+
+	rec := serveLevel(h, "GET", "/level", "", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, LevelResponse{Level: "debug"}, decodeLevel(t, rec))
+
+	req := httptest.NewRequest("GET", "/level", nil)
+	req.Header.Set("Accept", "text/plain")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "debug\n", rec.Body.String())
+}
+
+func TestLevelHandlerSetJSON(t *testing.T) {
+	logger, _ := newLogger()
+	h := LevelHandler(logger)
+
+	rec := serveLevel(h, "PUT", "/level", "application/json", `{"level":"warn"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "warn", decodeLevel(t, rec).Level)
+	assert.Equal(t, log.WarnLevel, logger.GetLevel())
+}
+
+func TestLevelHandlerSetPlainText(t *testing.T) {
+	logger, _ := newLogger()
+	h := LevelHandler(logger)
+
+	rec := serveLevel(h, "POST", "/level", "text/plain", "ERROR\n")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, log.ErrorLevel, logger.GetLevel())
+}
+
+func TestLevelHandlerErrors(t *testing.T) {
+	logger, _ := newLogger()
+	h := LevelHandler(logger)
+
+	for _, body := range []string{"", "loud", `{"level":`, `{"level":"info","ttl":"soon"}`, `{"level":"info","ttl":"-1m"}`} {
+		rec := serveLevel(h, "PUT", "/level", "", body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+		resp := decodeLevel(t, rec)
+		assert.NotEmpty(t, resp.Error, body)
+		assert.Equal(t, "debug", resp.Level, body)
+	}
+	assert.Equal(t, log.DebugLevel, logger.GetLevel())
+
+	rec := serveLevel(h, "DELETE", "/level", "", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, HEAD, POST, PUT", rec.Header().Get("Allow"))
+}
+
+func waitLevel(t *testing.T, logger *log.Logger, want string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for log.LevelString(logger.GetLevel()) != want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	assert.Equal(t, want, log.LevelString(logger.GetLevel()))
+}
+
+func TestLevelHandlerTTL(t *testing.T) {
+	logger, _ := newLogger()
+	logger.SetLevel(log.InfoLevel)
+	h := LevelHandler(logger)
+
+	rec := serveLevel(h, "PUT", "/level", "application/json", `{"level":"debug","ttl":"50ms"}`)
+	resp := decodeLevel(t, rec)
+	assert.Equal(t, "debug", resp.Level)
+	assert.Equal(t, "info", resp.RevertTo)
+	assert.NotNil(t, resp.RevertAt)
+
+	// A second change keeps the original level to revert to.
+	rec = serveLevel(h, "PUT", "/level?ttl=50ms", "text/plain", "warn")
+	assert.Equal(t, "info", decodeLevel(t, rec).RevertTo)
+
+	waitLevel(t, logger, "info")
+	assert.Equal(t, LevelResponse{Level: "info"}, decodeLevel(t, serveLevel(h, "GET", "/level", "", "")))
+}
+
+func TestLevelHandlerPermanentChangeCancelsTTL(t *testing.T) {
+	logger, _ := newLogger()
+	logger.SetLevel(log.InfoLevel)
+	h := LevelHandler(logger)
+
+	serveLevel(h, "PUT", "/level", "", `{"level":"debug","ttl":"20ms"}`)
+	serveLevel(h, "PUT", "/level", "", "error")
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, log.ErrorLevel, logger.GetLevel())
+}
@@ -0,0 +1,242 @@
+package http
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// Field names used by the middleware.
+const (
+	FieldBytes      = "http.bytes"
+	FieldDuration   = "http.duration_ms"
+	FieldHeader     = "http.header"
+	FieldMethod     = "http.method"
+	FieldPath       = "http.path"
+	FieldRemoteAddr = "http.remote_addr"
+	FieldRequestID  = "http.request_id"
+	FieldStatus     = "http.status"
+	FieldUserAgent  = "http.user_agent"
+)
+
+// DefaultRequestIDHeader is the header the request ID is read from and
+// written to.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// DefaultDenyHeaders lists the headers that are always redacted, even if
+// they are included in the allow list.
+var DefaultDenyHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	"Set-Cookie",
+	"X-Api-Key",
+}
+
+// StatusToLevel maps a response status code to the level the access log
+// entry is written at.
+type StatusToLevel func(status int) stdLogger.Level
+
+// DefaultStatusToLevel logs server errors at error, client errors at warn
+// and everything else at info.
+func DefaultStatusToLevel(status int) stdLogger.Level {
+	switch {
+	case status >= 500:
+		return log.ErrorLevel
+	case status >= 400:
+		return log.WarnLevel
+	}
+	return log.InfoLevel
+}
+
+// Option configures the middleware.
+type Option func(*options)
+
+type options struct {
+	allow           map[string]bool
+	deny            map[string]bool
+	requestIDHeader string
+	statusToLevel   StatusToLevel
+}
+
+// WithAllowHeaders adds request headers to the access log. Headers are
+// logged as `http.header.<canonical name>`.
+func WithAllowHeaders(headers ...string) Option {
+	return func(o *options) {
+		for _, header := range headers {
+			o.allow[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+}
+
+// WithDenyHeaders adds headers to the deny list. A denied header that is
+// also allowed is logged with its value replaced by `log.Redacted`, so it
+// can be seen that the header was present without leaking its value.
+func WithDenyHeaders(headers ...string) Option {
+	return func(o *options) {
+		for _, header := range headers {
+			o.deny[http.CanonicalHeaderKey(header)] = true
+		}
+	}
+}
+
+// WithRequestIDHeader sets the header the request ID is read from and
+// written to. If the request does not carry an ID a random one is generated.
+func WithRequestIDHeader(header string) Option {
+	return func(o *options) {
+		o.requestIDHeader = header
+	}
+}
+
+// WithStatusToLevel sets the function used to choose the level of the access
+// log entry.
+func WithStatusToLevel(fn StatusToLevel) Option {
+	return func(o *options) {
+		o.statusToLevel = fn
+	}
+}
+
+// Middleware returns a function that wraps a handler with `Handler`.
+func Middleware(logger log.FieldLogger, opts ...Option) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Handler(logger, next, opts...)
+	}
+}
+
+// Handler wraps next so that one access log entry is written through logger
+// for every request. The request context carries a request-scoped entry with
+// the method, path, remote address and request ID already set, retrieve it
+// with `log.FromContext(r.Context())`.
+func Handler(logger log.FieldLogger, next http.Handler, opts ...Option) http.Handler {
+	o := &options{
+		allow:           map[string]bool{},
+		deny:            map[string]bool{},
+		requestIDHeader: DefaultRequestIDHeader,
+		statusToLevel:   DefaultStatusToLevel,
+	}
+	WithDenyHeaders(DefaultDenyHeaders...)(o)
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(o.requestIDHeader)
+		if "" == requestID {
+			requestID = newRequestID()
+		}
+		w.Header().Set(o.requestIDHeader, requestID)
+
+		entry := logger.WithFields(log.Fields{
+			FieldMethod:     r.Method,
+			FieldPath:       r.URL.Path,
+			FieldRemoteAddr: r.RemoteAddr,
+			FieldRequestID:  requestID,
+		}).WithContext(r.Context())
+
+		rw := &responseWriter{ResponseWriter: w}
+		next.ServeHTTP(rw, r.WithContext(log.NewContext(r.Context(), entry)))
+
+		fields := log.Fields{
+			FieldBytes:     rw.bytes,
+			FieldDuration:  float64(time.Since(start)) / float64(time.Millisecond),
+			FieldStatus:    rw.status(),
+			FieldUserAgent: r.UserAgent(),
+		}
+		for header := range o.allow {
+			values, ok := r.Header[header]
+			if !ok {
+				continue
+			}
+			value := strings.Join(values, ", ")
+			if o.deny[header] {
+				value = log.Redacted
+			}
+			fields[FieldHeader+"."+header] = value
+		}
+
+		msg := fmt.Sprintf("%s %s", r.Method, r.URL.Path)
+		entry = entry.WithFields(fields)
+		switch o.statusToLevel(rw.status()) {
+		case log.PanicLevel, log.FatalLevel, log.ErrorLevel:
+			entry.Error(msg)
+		case log.WarnLevel:
+			entry.Warn(msg)
+		case log.InfoLevel:
+			entry.Info(msg)
+		default:
+			entry.Debug(msg)
+		}
+	})
+}
+
+// newRequestID returns a random 16 byte hex encoded request ID.
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); nil != err {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// responseWriter records the status code and number of bytes written to an
+// http.ResponseWriter.
+type responseWriter struct {
+	http.ResponseWriter
+	code  int
+	bytes int
+}
+
+func (w *responseWriter) status() int {
+	if 0 == w.code {
+		return http.StatusOK
+	}
+	return w.code
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *responseWriter) WriteHeader(code int) {
+	if 0 == w.code {
+		w.code = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if 0 == w.code {
+		w.code = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Flush implements http.Flusher if the wrapped writer supports it.
+func (w *responseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker if the wrapped writer supports it.
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, fmt.Errorf("http.Hijacker not implemented by %T", w.ResponseWriter)
+}
+
+// Unwrap returns the wrapped http.ResponseWriter.
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,166 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type logLine struct {
+	Data  map[string]interface{} `json:"data"`
+	Level string                 `json:"level"`
+	Msg   string                 `json:"msg"`
+}
+
+func newLogger() (*log.Logger, *bytes.Buffer) {
+	buf := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = buf
+	logger.Formatter = &log.JSONFormatter{DisableTTY: true}
+	logger.SetLevel(log.DebugLevel)
+	return logger, buf
+}
+
+func lines(t *testing.T, buf *bytes.Buffer) []logLine {
+	result := []logLine{}
+	dec := json.NewDecoder(buf)
+	for {
+		line := logLine{}
+		if err := dec.Decode(&line); io.EOF == err {
+			break
+		} else if nil != err {
+			t.Fatal(err)
+		}
+		result = append(result, line)
+	}
+	return result
+}
+
+func TestHandlerAccessLog(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("in handler")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("POST", "/things?x=1", nil)
+	req.Header.Set("User-Agent", "test-agent")
+	req.Header.Set(DefaultRequestIDHeader, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "req-1", rec.Header().Get(DefaultRequestIDHeader))
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "in handler", logs[0].Msg)
+		assert.Equal(t, "req-1", logs[0].Data[FieldRequestID])
+		assert.Equal(t, "/things", logs[0].Data[FieldPath])
+
+		assert.Equal(t, "info", logs[1].Level)
+		assert.Equal(t, "POST /things", logs[1].Msg)
+		assert.Equal(t, "POST", logs[1].Data[FieldMethod])
+		assert.Equal(t, 201.0, logs[1].Data[FieldStatus])
+		assert.Equal(t, 5.0, logs[1].Data[FieldBytes])
+		assert.Equal(t, "test-agent", logs[1].Data[FieldUserAgent])
+		assert.Equal(t, "192.0.2.1:1234", logs[1].Data[FieldRemoteAddr])
+		assert.Contains(t, logs[1].Data, FieldDuration)
+	}
+}
+
+func TestHandlerGeneratesRequestID(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Middleware(logger, WithRequestIDHeader("X-Trace"))(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/missing", nil))
+
+	id := rec.Header().Get("X-Trace")
+	assert.Len(t, id, 32)
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "warn", logs[0].Level)
+		assert.Equal(t, id, logs[0].Data[FieldRequestID])
+		assert.Equal(t, 404.0, logs[0].Data[FieldStatus])
+	}
+}
+
+func TestHandlerStatusLevels(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}), WithStatusToLevel(func(status int) stdLogger.Level {
+		return log.DebugLevel
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "debug", logs[0].Level)
+	}
+}
+
+func TestHandlerHeaders(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		WithAllowHeaders("accept", "authorization", "x-tenant"),
+		WithDenyHeaders("X-Tenant"),
+	)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer secret-token")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set("X-Other", "other")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.NotContains(t, buf.String(), "secret-token")
+	assert.NotContains(t, buf.String(), "acme")
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "application/json", logs[0].Data[FieldHeader+".Accept"])
+		assert.Equal(t, log.Redacted, logs[0].Data[FieldHeader+".Authorization"])
+		assert.Equal(t, log.Redacted, logs[0].Data[FieldHeader+".X-Tenant"])
+		assert.NotContains(t, logs[0].Data, FieldHeader+".X-Other")
+	}
+}
+
+func TestHandlerDeniedHeadersDontChangeRedaction(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		log.FromContext(r.Context()).Info("hello")
+	}), WithAllowHeaders("Authorization"))
+
+	// A client must not be able to choose what is masked in other output.
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer e")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	logs := lines(t, buf)
+	if assert.Len(t, logs, 2) {
+		assert.Equal(t, "hello", logs[0].Msg)
+		assert.Equal(t, log.Redacted, logs[1].Data[FieldHeader+".Authorization"])
+	}
+	assert.NotContains(t, buf.String(), "Bearer e")
+}
+
+func TestHandlerCaller(t *testing.T) {
+	logger, buf := newLogger()
+	handler := Handler(logger, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	line := map[string]interface{}{}
+	if assert.NoError(t, json.Unmarshal(buf.Bytes(), &line)) {
+		assert.Contains(t, line["caller"], "middleware.go")
+	}
+}
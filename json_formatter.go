@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"strings"
+	"sort"
 	"sync"
 	"text/template"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
 )
 
 var funcMap = template.FuncMap{
@@ -132,9 +134,32 @@ type JSONFormatter struct {
 	// TimestampFormat allows a custom timestamp format to be used.
 	TimestampFormat string
 
+	// Theme sets the TTY colors, e.g. `LightTheme()`. If nil, the color
+	// variables such as `DEFAULTColor` are used.
+	Theme *Theme
+
+	// ColorProfile sets the colors the terminal can display. By default it
+	// is detected from the environment, see `DetectColorProfile`.
+	ColorProfile ColorProfile
+
+	// KeyOrder sets the order of the default fields in non-TTY output, e.g.
+	// `[]FieldLabel{LabelTime, LabelLevel, LabelMsg}`. Fields that are not
+	// listed follow sorted by key.
+	KeyOrder []FieldLabel
+
+	// KeepFieldOrder writes data fields in non-TTY output in the order they
+	// were added to the entry instead of sorted by key.
+	KeepFieldOrder bool
+
 	// Flag noting whether the logger's output is to a terminal
 	isTerminal bool
 
+	// Flag noting whether CLICOLOR_FORCE forces TTY formatted output
+	forceColor bool
+
+	// The color profile detected from the environment
+	colorProfile ColorProfile
+
 	sync.Once
 }
 
@@ -142,18 +167,38 @@ func (f *JSONFormatter) init(entry *Entry) {
 	if entry.Logger != nil {
 		f.isTerminal = checkIfTerminal(entry.Logger.Out)
 	}
+	f.forceColor = forceColor()
+	f.colorProfile = outputColorProfile(f.isTerminal)
+}
+
+// colors returns the TTY colors of an entry at a level.
+func (f *JSONFormatter) colors(level stdLogger.Level) colors {
+	profile := f.ColorProfile
+	if ColorsAuto == profile {
+		profile = f.colorProfile
+	}
+	return getColors(f.Theme, profile, level)
+}
+
+// NeedsCaller implements CallerFormatter.
+func (f *JSONFormatter) NeedsCaller() (caller, trace bool) {
+	return !f.DisableCaller, f.EnableTrace
 }
 
 // Format renders a single log entry
 func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 	var err error
 	var serialized []byte
-	isTTY := (f.ForceTTY || f.isTerminal) && !f.DisableTTY
+	f.Do(func() { f.init(entry) })
+	isTTY := (f.ForceTTY || f.isTerminal || f.forceColor) && !f.DisableTTY
 
 	prefixFieldClashes(entry.Data, f.FieldMap)
-	f.Do(func() { f.init(entry) })
 
-	data := getData(entry, f.FieldMap, f.EscapeHTML, isTTY)
+	caller, trace := f.NeedsCaller()
+	data := getEntryData(entry, f.FieldMap, f.EscapeHTML, isTTY, caller, trace)
+	if isTTY {
+		data.Color = f.colors(entry.Level)
+	}
 
 	if f.DisableTimestamp {
 		data.Timestamp = ""
@@ -186,6 +231,13 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 		} else {
 			logLine = &bytes.Buffer{}
 		}
+		for _, field := range data.Typed {
+			value, err := field.appendJSON(nil, f.EscapeHTML)
+			if nil != err {
+				return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
+			}
+			data.Data[field.Key] = json.RawMessage(value)
+		}
 		err = jsonTermTemplate.Execute(logLine, data)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to marshal fields to JSON, %v", err)
@@ -224,7 +276,15 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 				data.Data[k] = e.Error()
 			}
 		}
-		jsonData[f.FieldMap.resolve(LabelData)] = data.Data
+		if f.KeepFieldOrder || 0 < len(data.Typed) {
+			jsonData[f.FieldMap.resolve(LabelData)] = orderedFields{
+				keys:   dataKeys(entry, data.Data, data.Typed, f.KeepFieldOrder),
+				values: data.Data,
+				typed:  data.Typed,
+			}
+		} else {
+			jsonData[f.FieldMap.resolve(LabelData)] = data.Data
+		}
 
 		if nil != data.Err {
 			jsonData[f.FieldMap.resolve(LabelError)] = data.Err
@@ -233,8 +293,17 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 		buf := new(bytes.Buffer)
 		encoder := json.NewEncoder(buf)
 		encoder.SetEscapeHTML(f.EscapeHTML)
-		err = encoder.Encode(jsonData)
-		serialized = []byte(strings.Trim(buf.String(), "\n"))
+		if 0 == len(f.KeyOrder) && !f.KeepFieldOrder && 0 == len(data.Typed) {
+			// encoding/json sorts map keys, which is the default order.
+			err = encoder.Encode(jsonData)
+			serialized = bytes.TrimRight(buf.Bytes(), "\n")
+		} else {
+			err = f.encode(buf, encoder, orderedFields{
+				keys:   f.keys(jsonData),
+				values: jsonData,
+			})
+			serialized = buf.Bytes()
+		}
 	}
 
 	if err != nil {
@@ -242,3 +311,70 @@ func (f *JSONFormatter) Format(entry *Entry) ([]byte, error) {
 	}
 	return append(serialized, '\n'), nil
 }
+
+// orderedFields is a JSON object written in the order of its keys. Typed
+// fields are encoded directly.
+type orderedFields struct {
+	keys   []string
+	values map[string]interface{}
+	typed  []Field
+}
+
+// keys returns the keys of the relabeled default fields, the fields listed
+// in `KeyOrder` first and the rest sorted.
+func (f *JSONFormatter) keys(jsonData map[string]interface{}) []string {
+	keys := make([]string, 0, len(jsonData))
+	seen := map[string]bool{}
+	for _, label := range f.KeyOrder {
+		key := f.FieldMap.resolve(label)
+		if _, ok := jsonData[key]; ok && !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	ordered := len(keys)
+	for key := range jsonData {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys[ordered:])
+	return keys
+}
+
+// encode writes v as compact JSON with encoder, which writes to buf. Objects
+// are written in key order.
+func (f *JSONFormatter) encode(buf *bytes.Buffer, encoder *json.Encoder, v interface{}) error {
+	if fields, ok := v.(orderedFields); ok {
+		buf.WriteByte('{')
+		for i, k := range fields.keys {
+			if 0 < i {
+				buf.WriteByte(',')
+			}
+			// Keys and typed values are appended to the buffer's spare
+			// capacity, Write then copies them onto themselves.
+			buf.Write(appendJSONString(buf.Bytes()[buf.Len():], k, f.EscapeHTML))
+			buf.WriteByte(':')
+			if j := typedIndex(fields.typed, k); 0 <= j {
+				value, err := fields.typed[j].appendJSON(buf.Bytes()[buf.Len():], f.EscapeHTML)
+				if nil != err {
+					return err
+				}
+				buf.Write(value)
+				continue
+			}
+			if err := f.encode(buf, encoder, fields.values[k]); nil != err {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+		return nil
+	}
+
+	if err := encoder.Encode(v); nil != err {
+		return err
+	}
+	// Encode terminates the value with a newline.
+	buf.Truncate(buf.Len() - 1)
+	return nil
+}
@@ -0,0 +1,118 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func keyOrderLine(formatter Formatter) string {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = formatter
+	logger.WithField("z", 1).
+		WithField("a", Fields{"y": 1, "b": 2}).
+		With(String("m", "x")).
+		WithError(errors.New("boom")).
+		Info("hello")
+	return buffer.String()
+}
+
+// assertOrder asserts the substrings appear in s in order.
+func assertOrder(t *testing.T, s string, substrings ...string) {
+	last := -1
+	for _, sub := range substrings {
+		i := strings.Index(s, sub)
+		if !assert.True(t, i > last, "%q out of order in %s", sub, s) {
+			return
+		}
+		last = i
+	}
+}
+
+func TestJSONFormatterKeyOrder(t *testing.T) {
+	line := keyOrderLine(&JSONFormatter{DisableTTY: true, DisableCaller: true, DisableHostname: true})
+	assert.Regexp(t, `^\{"data":\{"a":\{"b":2,"y":1\},"m":"x","z":1\},"error":"boom","level":"info","msg":"hello","time":"[^"]+"\}\n$`, line)
+
+	line = keyOrderLine(&JSONFormatter{
+		DisableTTY:     true,
+		KeyOrder:       []FieldLabel{LabelTime, LabelLevel, LabelMsg},
+		KeepFieldOrder: true,
+		FieldMap:       FieldMap{LabelMsg: "message"},
+	})
+	assert.Regexp(t, `^\{"time":"[^"]+","level":"info","message":"hello","caller":"[^"]+","data":\{"z":1,"a":\{"b":2,"y":1\},"m":"x"\},"error":"boom","host":"[^"]*"\}\n$`, line)
+}
+
+func TestTextFormatterKeyOrder(t *testing.T) {
+	line := keyOrderLine(&TextFormatter{DisableTTY: true})
+	assertOrder(t, line, "time=", "level=", "msg=", "error=", `data.a=`, `data.m=`, `data.z=`, "caller=", "host=")
+
+	line = keyOrderLine(&TextFormatter{DisableTTY: true, KeyOrder: []FieldLabel{LabelMsg, LabelData}, KeepFieldOrder: true})
+	assert.True(t, strings.HasPrefix(line, `msg="hello" data.z=1 data.a={"b":2,"y":1} data.m="x" time=`), line)
+	assertOrder(t, line, "time=", "level=", "error=", "caller=", "host=")
+
+	line = keyOrderLine(&TextFormatter{ForceTTY: true, ColorProfile: ColorsNone, KeepFieldOrder: true})
+	assertOrder(t, line, "z=", "a=", "m=")
+}
+
+func TestStdFormatterKeyOrder(t *testing.T) {
+	line := keyOrderLine(&StdFormatter{})
+	assert.Regexp(t, regexp.MustCompile(`^\S+ \S+ hello level="info" data.a=\{"b":2,"y":1\} data.m="x" data.z=1 error="boom" caller=`), line)
+	assert.Equal(t, 1, strings.Count(line, "boom"), line)
+
+	line = keyOrderLine(&StdFormatter{KeyOrder: []FieldLabel{LabelError}, KeepFieldOrder: true})
+	assert.Regexp(t, regexp.MustCompile(`^\S+ \S+ hello error="boom" level="info" data.z=1 data.a=\{"b":2,"y":1\} data.m="x" caller=`), line)
+}
+
+func TestLogfmtFormatterKeyOrder(t *testing.T) {
+	line := keyOrderLine(&LogfmtFormatter{DisableCaller: true, DisableHostname: true})
+	assert.Regexp(t, `^time=\S+ level=info msg=hello error=boom data.a.b=2 data.a.y=1 data.m=x data.z=1\n$`, line)
+
+	line = keyOrderLine(&LogfmtFormatter{
+		DisableCaller:   true,
+		DisableHostname: true,
+		KeyOrder:        []FieldLabel{LabelMsg, LabelLevel},
+		KeepFieldOrder:  true,
+	})
+	assert.Regexp(t, `^msg=hello level=info time=\S+ error=boom data.z=1 data.a.b=2 data.a.y=1 data.m=x\n$`, line)
+}
+
+func TestTemplateFormatterKeepFieldOrder(t *testing.T) {
+	text := `{{range .Fields}}{{.Key}} {{end}}`
+	formatter, err := NewTemplateFormatter(text, TemplateOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "a m z \n", keyOrderLine(formatter))
+
+	formatter, err = NewTemplateFormatter(text, TemplateOptions{KeepFieldOrder: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "z a m \n", keyOrderLine(formatter))
+}
+
+func TestDataKeys(t *testing.T) {
+	logger := New()
+	logger.SetBaseFields(Fields{"service": "api", "base": true})
+	entry := NewEntry(logger).
+		WithField("c", 1).
+		With(Int("b", 2)).
+		WithFields(Fields{"y": 3, "x": 4}).
+		With(Int("c", 5))
+	data := entry.AllData()
+	data["hook"] = true
+
+	assert.Equal(t, []string{"b", "base", "c", "hook", "service", "x", "y"}, dataKeys(entry, data, nil, false))
+	assert.Equal(t, []string{"base", "service", "c", "b", "x", "y", "hook"}, dataKeys(entry, data, nil, true))
+
+	// The order is recorded whatever the logger's formatter, e.g. for a
+	// hook's formatter, and keys already set keep their place.
+	entry = logger.WithField("service", "db").WithField("z", 1).WithField("a", 2)
+	buf, err := (&LogfmtFormatter{KeepFieldOrder: true, DisableTimestamp: true}).Format(entry)
+	assert.Nil(t, err)
+	assert.Contains(t, string(buf), "data.base=true data.service=db data.z=1 data.a=2")
+	assert.Equal(t, []FieldLabel{LabelMsg, LabelTime, LabelLevel, LabelError, LabelData, LabelCaller, LabelHost, LabelTrace},
+		keyOrder([]FieldLabel{LabelMsg, "unknown", LabelMsg}, defaultKeyOrder))
+}
@@ -0,0 +1,186 @@
+package log
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// SyslogSeverity is a syslog severity as defined by RFC 5424.
+type SyslogSeverity int
+
+// Syslog severities, most severe first.
+const (
+	SyslogEmergency SyslogSeverity = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+// LevelInfo describes a log level.
+type LevelInfo struct {
+	// Level is the level's value, which also orders levels by severity.
+	// Lower values are more severe, an entry is logged if its level is not
+	// greater than the logger's level. Custom levels can use any value
+	// between the built-in ones, e.g. 35 for a level between Warn and Info.
+	Level stdLogger.Level
+
+	// Name is the level's name, written by formatters and accepted by
+	// `ParseLevel`. Names are matched case-insensitively.
+	Name string
+
+	// Aliases are additional names accepted by `ParseLevel`.
+	Aliases []string
+
+	// Color is the TTY color escape sequence for the level. If empty, the
+	// built-in levels use the color variables such as `DEBUGColor` and
+	// other levels use `DEFAULTColor`.
+	Color string
+
+	// Syslog is the syslog severity entries at the level are sent with.
+	Syslog SyslogSeverity
+}
+
+// levelRegistry is an immutable snapshot of the registered levels.
+type levelRegistry struct {
+	byLevel map[stdLogger.Level]LevelInfo
+	byName  map[string]stdLogger.Level
+	levels  []stdLogger.Level
+
+	// nameWidth is the length of the longest level name.
+	nameWidth int
+}
+
+var (
+	levelsMu sync.Mutex
+	levels   atomic.Value
+)
+
+func init() {
+	registry := &levelRegistry{
+		byLevel: map[stdLogger.Level]LevelInfo{},
+		byName:  map[string]stdLogger.Level{},
+	}
+	for _, info := range []LevelInfo{
+		{Level: FatalLevel, Name: "fatal", Syslog: SyslogCritical},
+		{Level: PanicLevel, Name: "panic", Syslog: SyslogCritical},
+		{Level: ErrorLevel, Name: "error", Syslog: SyslogError},
+		{Level: WarnLevel, Name: "warn", Aliases: []string{"warning"}, Syslog: SyslogWarning},
+		{Level: InfoLevel, Name: "info", Syslog: SyslogInfo},
+		{Level: DebugLevel, Name: "debug", Syslog: SyslogDebug},
+		{Level: TraceLevel, Name: "trace", Syslog: SyslogDebug},
+	} {
+		registry = registry.with(info)
+	}
+	levels.Store(registry)
+}
+
+// RegisterLevel adds a custom level, or replaces the definition of an
+// existing level with the same value. The level's names must not be used by
+// another level.
+//
+//	const NoticeLevel stdLogger.Level = 35
+//
+//	log.RegisterLevel(log.LevelInfo{
+//		Level:  NoticeLevel,
+//		Name:   "notice",
+//		Color:  "\033[38;5;45m",
+//		Syslog: log.SyslogNotice,
+//	})
+//	logger.Log(NoticeLevel, "disk 80% full")
+func RegisterLevel(info LevelInfo) error {
+	if "" == info.Name {
+		return fmt.Errorf("level %d has no name", info.Level)
+	}
+	levelsMu.Lock()
+	defer levelsMu.Unlock()
+	registry := getLevels()
+	for _, name := range append([]string{info.Name}, info.Aliases...) {
+		if level, ok := registry.byName[strings.ToLower(name)]; ok && level != info.Level {
+			return fmt.Errorf("level name %q is used by level %d", name, level)
+		}
+	}
+	levels.Store(registry.with(info))
+	return nil
+}
+
+// LookupLevel returns the definition of a level.
+func LookupLevel(level stdLogger.Level) (LevelInfo, bool) {
+	info, ok := getLevels().byLevel[level]
+	return info, ok
+}
+
+// RegisteredLevels returns every registered level, built-in and custom, most
+// severe first. Hooks that should fire for every level can return it from
+// `Levels`.
+func RegisteredLevels() []stdLogger.Level {
+	registered := getLevels().levels
+	result := make([]stdLogger.Level, len(registered))
+	copy(result, registered)
+	return result
+}
+
+func getLevels() *levelRegistry {
+	return levels.Load().(*levelRegistry)
+}
+
+// with returns a copy of the registry with a level added or replaced.
+func (r *levelRegistry) with(info LevelInfo) *levelRegistry {
+	registry := &levelRegistry{
+		byLevel: make(map[stdLogger.Level]LevelInfo, len(r.byLevel)+1),
+		byName:  make(map[string]stdLogger.Level, len(r.byName)+1),
+	}
+	for level, existing := range r.byLevel {
+		if level != info.Level {
+			registry.add(existing)
+		}
+	}
+	registry.add(info)
+	sort.Slice(registry.levels, func(i, j int) bool {
+		return registry.levels[i] < registry.levels[j]
+	})
+	return registry
+}
+
+func (r *levelRegistry) add(info LevelInfo) {
+	r.byLevel[info.Level] = info
+	r.levels = append(r.levels, info.Level)
+	if width := utf8.RuneCountInString(info.Name); width > r.nameWidth {
+		r.nameWidth = width
+	}
+	r.byName[strings.ToLower(info.Name)] = info.Level
+	for _, alias := range info.Aliases {
+		r.byName[strings.ToLower(alias)] = info.Level
+	}
+}
+
+// getLevelColor returns the TTY color of a level.
+func getLevelColor(level stdLogger.Level) string {
+	if info, ok := LookupLevel(level); ok && "" != info.Color {
+		return info.Color
+	}
+	switch level {
+	case TraceLevel:
+		return TRACEColor
+	case DebugLevel:
+		return DEBUGColor
+	case WarnLevel:
+		return WARNColor
+	case ErrorLevel:
+		return ERRORColor
+	case FatalLevel:
+		return FATALColor
+	case PanicLevel:
+		return PANICColor
+	}
+	return DEFAULTColor
+}
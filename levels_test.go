@@ -0,0 +1,177 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNoticeLevel stdLogger.Level = 35
+
+// registerNotice registers a custom level and returns a function restoring
+// the registry.
+func registerNotice(t *testing.T) func() {
+	registry := getLevels()
+	assert.Nil(t, RegisterLevel(LevelInfo{
+		Level:   testNoticeLevel,
+		Name:    "notice",
+		Aliases: []string{"note"},
+		Color:   "\033[38;5;45m",
+		Syslog:  SyslogNotice,
+	}))
+	return func() {
+		levels.Store(registry)
+	}
+}
+
+func TestAllLevels(t *testing.T) {
+	assert.Contains(t, AllLevels, DebugLevel)
+	assert.Contains(t, AllLevels, TraceLevel)
+	assert.Equal(t, []stdLogger.Level{
+		FatalLevel, PanicLevel, ErrorLevel, WarnLevel, InfoLevel, DebugLevel, TraceLevel,
+	}, RegisteredLevels())
+
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetLevel(TraceLevel)
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+	logger.Debug("debug")
+	logger.Trace("trace")
+	assert.Len(t, hook.Entries, 2)
+}
+
+func TestTraceLevel(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+
+	logger.SetLevel(DebugLevel)
+	logger.Trace("hidden")
+	logger.Tracef("hidden %d", 1)
+	logger.WithField("k", "v").Traceln("hidden")
+	assert.Empty(t, buffer.String())
+
+	logger.SetLevel(TraceLevel)
+	logger.WithField("k", "v").Tracef("shown %d", 1)
+	data := logData{}
+	assert.Nil(t, json.Unmarshal(buffer.Bytes(), &data))
+	assert.Equal(t, "trace", data.Level)
+	assert.Equal(t, "shown 1", data.Message)
+}
+
+func TestRegisterLevel(t *testing.T) {
+	defer registerNotice(t)()
+
+	assert.Equal(t, "notice", LevelString(testNoticeLevel))
+	for _, name := range []string{"notice", "NOTICE", "note"} {
+		level, err := ParseLevel(name)
+		assert.Nil(t, err)
+		assert.Equal(t, testNoticeLevel, level)
+	}
+	info, ok := LookupLevel(testNoticeLevel)
+	assert.True(t, ok)
+	assert.Equal(t, SyslogNotice, info.Syslog)
+	assert.Equal(t, []stdLogger.Level{
+		FatalLevel, PanicLevel, ErrorLevel, WarnLevel, testNoticeLevel, InfoLevel, DebugLevel, TraceLevel,
+	}, RegisteredLevels())
+
+	// Names must be unique, the same level can be redefined.
+	assert.NotNil(t, RegisterLevel(LevelInfo{Level: 36, Name: "Notice"}))
+	assert.NotNil(t, RegisterLevel(LevelInfo{Level: 36, Name: "audit", Aliases: []string{"info"}}))
+	assert.NotNil(t, RegisterLevel(LevelInfo{Level: 36}))
+	assert.Nil(t, RegisterLevel(LevelInfo{Level: testNoticeLevel, Name: "notice", Syslog: SyslogWarning}))
+	info, _ = LookupLevel(testNoticeLevel)
+	assert.Equal(t, SyslogWarning, info.Syslog)
+	_, err := ParseLevel("note")
+	assert.NotNil(t, err)
+}
+
+func TestLogCustomLevel(t *testing.T) {
+	defer registerNotice(t)()
+
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTTY: true, DisableHostname: true}
+	hook := &RecordHook{}
+	logger.Hooks.Add(&levelsHook{hook: hook, levels: RegisteredLevels()})
+
+	logger.SetLevel(WarnLevel)
+	logger.Log(testNoticeLevel, "hidden")
+	assert.Empty(t, buffer.String())
+
+	logger.SetLevel(InfoLevel)
+	logger.WithField("disk", 80).Logf(testNoticeLevel, "disk %d%% full", 80)
+	assert.Contains(t, buffer.String(), `level="notice"`)
+	assert.Contains(t, buffer.String(), `msg="disk 80% full"`)
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, testNoticeLevel, hook.Entries[0].Level)
+	}
+
+	buffer.Reset()
+	logger.Logln(InfoLevel, "a", 1)
+	assert.Contains(t, buffer.String(), `msg="a 1"`)
+
+	buffer.Reset()
+	logger.Formatter = &TextFormatter{ForceTTY: true}
+	logger.Log(testNoticeLevel, "colored")
+	assert.True(t, strings.HasPrefix(buffer.String(), "\033[38;5;45mnotice"), buffer.String())
+
+	// The writer logs from its own goroutine, wait for the line on a channel
+	// instead of reading the buffer while it is written.
+	lines := make(chanWriter, 1)
+	logger.Out = lines
+	w := logger.WriterLevel(testNoticeLevel)
+	_, err := w.Write([]byte("from writer\n"))
+	assert.Nil(t, err)
+	w.Close()
+	select {
+	case line := <-lines:
+		assert.Contains(t, line, "notice")
+		assert.Contains(t, line, "from writer")
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the writer")
+	}
+}
+
+// chanWriter sends every write to the channel.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func TestLogBuiltinLevels(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	code := -1
+	logger.ExitFunc = func(c int) {
+		code = c
+	}
+	logger.Log(FatalLevel, "fatal")
+	assert.Equal(t, 1, code)
+	assert.Panics(t, func() {
+		logger.Log(PanicLevel, "panic")
+	})
+}
+
+type levelsHook struct {
+	hook   *RecordHook
+	levels []stdLogger.Level
+}
+
+func (h *levelsHook) Levels() []stdLogger.Level {
+	return h.levels
+}
+
+func (h *levelsHook) Fire(entry *Entry) error {
+	return h.hook.Fire(entry)
+}
@@ -12,53 +12,35 @@ import (
 type Fields map[string]interface{}
 
 // LevelString convert the Level to a human readable string. E.g. PanicLevel becomes "panic".
+// Custom levels are named by `RegisterLevel`.
 func LevelString(level stdLogger.Level) string {
-	switch level {
-	case DebugLevel:
-		return "debug"
-	case InfoLevel:
-		return "info"
-	case WarnLevel:
-		return "warn"
-	case ErrorLevel:
-		return "error"
-	case FatalLevel:
-		return "fatal"
-	case PanicLevel:
-		return "panic"
+	if info, ok := LookupLevel(level); ok {
+		return info.Name
 	}
-
 	return "unknown"
 }
 
-// ParseLevel takes a string level and returns the log level constant.
+// ParseLevel takes a string level and returns the log level constant. The
+// names and aliases of custom levels are accepted too.
 func ParseLevel(lvl string) (stdLogger.Level, error) {
-	switch strings.ToLower(lvl) {
-	case "panic":
-		return PanicLevel, nil
-	case "fatal":
-		return FatalLevel, nil
-	case "error":
-		return ErrorLevel, nil
-	case "warn", "warning":
-		return WarnLevel, nil
-	case "info":
-		return InfoLevel, nil
-	case "debug":
-		return DebugLevel, nil
+	if level, ok := getLevels().byName[strings.ToLower(lvl)]; ok {
+		return level, nil
 	}
 
 	var l stdLogger.Level
 	return l, fmt.Errorf("not a valid log Level: %q", lvl)
 }
 
-// AllLevels is a constant exposing all logging levels.
+// AllLevels is a constant exposing all built-in logging levels. Use
+// `RegisteredLevels` to include custom levels.
 var AllLevels = []stdLogger.Level{
 	stdLogger.Panic,
 	stdLogger.Fatal,
 	stdLogger.Error,
 	stdLogger.Warn,
 	stdLogger.Info,
+	stdLogger.Debug,
+	stdLogger.Trace,
 }
 
 // These are the standard logging levels. You can set the logging level to log
@@ -80,6 +62,9 @@ const (
 	InfoLevel = stdLogger.Info
 	// DebugLevel level. Usually only enabled when debugging. Very verbose logging.
 	DebugLevel = stdLogger.Debug
+	// TraceLevel level. Finer grained than Debug, usually only enabled to follow
+	// the execution of a specific code path.
+	TraceLevel = stdLogger.Trace
 )
 
 // Won't compile if StdLogger can't be realized by a log.Logger
@@ -319,6 +319,8 @@ func TestConvertLevelToString(t *testing.T) {
 	assert.Equal(t, "error", LevelString(ErrorLevel))
 	assert.Equal(t, "fatal", LevelString(FatalLevel))
 	assert.Equal(t, "panic", LevelString(PanicLevel))
+	assert.Equal(t, "trace", LevelString(TraceLevel))
+	assert.Equal(t, "unknown", LevelString(99))
 }
 
 func TestParseLevel(t *testing.T) {
@@ -378,6 +380,10 @@ func TestParseLevel(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, DebugLevel, l)
 
+	l, err = ParseLevel("Trace")
+	assert.Nil(t, err)
+	assert.Equal(t, TraceLevel, l)
+
 	_, err = ParseLevel("invalid")
 	assert.Equal(t, "not a valid log Level: \"invalid\"", err.Error())
 }
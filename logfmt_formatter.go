@@ -0,0 +1,360 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// LogfmtFormatter formats logs into strict logfmt, as read by Loki, Heroku
+// and the go-logfmt family of parsers.
+//
+// By default keys are written in the order time, level, msg, error, data
+// fields sorted by key, caller, host and trace, see `KeyOrder` and
+// `KeepFieldOrder`. Values are only quoted when they need to be, quoted
+// values use JSON string escapes. Nested `Fields` and
+// `map[string]interface{}` values are flattened into dotted keys and
+// characters that are not valid in a key are replaced with an underscore.
+// Stack frames are written as a single trace value separated by newlines.
+//
+// Output can be decoded with `ParseLogfmt` or `LogfmtFormatter.Parse`.
+type LogfmtFormatter struct {
+	// DisableCaller disables caller data output.
+	DisableCaller bool
+
+	// DisableHostname disables hostname output.
+	DisableHostname bool
+
+	// DisableLevel disables level output.
+	DisableLevel bool
+
+	// DisableMessage disables message output.
+	DisableMessage bool
+
+	// DisableTimestamp disables timestamp output.
+	DisableTimestamp bool
+
+	// Enable full backtrace output.
+	EnableTrace bool
+
+	// FieldMap allows users to customize the names of keys for default
+	// fields. Data fields are prefixed with the `LabelData` key and a dot,
+	// map it to an empty string to write data fields at the top level:
+	//
+	// 	formatter := &LogfmtFormatter{FieldMap: FieldMap{
+	//      LabelData: "",
+	//      LabelMsg:  "message",
+	// 	}}
+	FieldMap FieldMap
+
+	// TimestampFormat allows a custom timestamp format to be used.
+	TimestampFormat string
+
+	// KeyOrder sets the order of the default fields, e.g.
+	// `[]FieldLabel{LabelTime, LabelLevel, LabelMsg}`. Fields that are not
+	// listed follow in the default order.
+	KeyOrder []FieldLabel
+
+	// KeepFieldOrder writes data fields in the order they were added to the
+	// entry instead of sorted by key.
+	KeepFieldOrder bool
+}
+
+// NeedsCaller implements CallerFormatter.
+func (f *LogfmtFormatter) NeedsCaller() (caller, trace bool) {
+	return !f.DisableCaller, f.EnableTrace
+}
+
+// Format renders a single log entry
+func (f *LogfmtFormatter) Format(entry *Entry) ([]byte, error) {
+	var logLine *bytes.Buffer
+	if entry.Buffer != nil {
+		logLine = entry.Buffer
+	} else {
+		logLine = &bytes.Buffer{}
+	}
+
+	caller, trace := f.NeedsCaller()
+	data := getEntryData(entry, f.FieldMap, false, false, caller, trace)
+
+	for _, label := range keyOrder(f.KeyOrder, defaultKeyOrder) {
+		switch label {
+		case LabelTime:
+			if !f.DisableTimestamp {
+				format := f.TimestampFormat
+				if "" == format {
+					format = defaultTimestampFormat
+				}
+				writeLogfmt(logLine, data.LabelTime, entry.Time.Format(format))
+			}
+		case LabelLevel:
+			if !f.DisableLevel {
+				writeLogfmt(logLine, data.LabelLevel, data.Level)
+			}
+		case LabelMsg:
+			if !f.DisableMessage {
+				writeLogfmt(logLine, data.LabelMsg, entry.Message)
+			}
+		case LabelError:
+			if nil != entry.Err {
+				writeLogfmt(logLine, data.LabelError, fmt.Sprintf("%-v", entry.Err))
+			}
+		case LabelData:
+			f.writeData(logLine, entry, data.LabelData)
+		case LabelCaller:
+			if !f.DisableCaller || f.EnableTrace {
+				if "" != data.Caller {
+					writeLogfmt(logLine, data.LabelCaller, data.Caller)
+				}
+			}
+		case LabelHost:
+			if !f.DisableHostname && "" != data.Hostname {
+				writeLogfmt(logLine, data.LabelHost, data.Hostname)
+			}
+		case LabelTrace:
+			if f.EnableTrace && 0 < len(data.Trace) {
+				writeLogfmt(logLine, data.LabelTrace, strings.Join(data.Trace, "\n"))
+			}
+		}
+	}
+
+	logLine.WriteByte('\n')
+	return logLine.Bytes(), nil
+}
+
+// writeData writes the data fields, nested fields are flattened. Typed
+// fields other than `Any` fields are encoded directly.
+func (f *LogfmtFormatter) writeData(logLine *bytes.Buffer, entry *Entry, labelData string) {
+	values := make(map[string]interface{}, len(entry.Data)+len(entry.TypedData))
+	for k, v := range entry.Data {
+		values[k] = v
+	}
+	// Typed fields replace map fields and earlier typed fields with the
+	// same key.
+	var typed []Field
+	for _, field := range entry.TypedData {
+		delete(values, field.Key)
+		if i := typedIndex(typed, field.Key); 0 <= i {
+			typed = append(typed[:i], typed[i+1:]...)
+		}
+		if AnyType == field.Type || UnknownType == field.Type {
+			values[field.Key] = field.Interface
+			continue
+		}
+		typed = append(typed, field)
+	}
+
+	fields := map[string]interface{}{}
+	keys := []string{}
+	if f.KeepFieldOrder {
+		// Flatten each field on its own to keep nested keys together.
+		for _, k := range dataKeys(entry, values, typed, true) {
+			if 0 <= typedIndex(typed, k) {
+				keys = append(keys, k)
+				continue
+			}
+			nested := map[string]interface{}{}
+			flattenLogfmt(nested, "", map[string]interface{}{k: values[k]})
+			nestedKeys := make([]string, 0, len(nested))
+			for nk, nv := range nested {
+				fields[nk] = nv
+				nestedKeys = append(nestedKeys, nk)
+			}
+			sort.Strings(nestedKeys)
+			keys = append(keys, nestedKeys...)
+		}
+	} else {
+		flattenLogfmt(fields, "", values)
+		for _, field := range typed {
+			delete(fields, field.Key)
+			keys = append(keys, field.Key)
+		}
+		for k := range fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+	}
+
+	reserved := f.reservedKeys()
+	var text []byte
+	for _, k := range keys {
+		key := k
+		if "" != labelData {
+			key = labelData + "." + k
+		} else if reserved[k] {
+			// Don't let fields shadow the default keys.
+			key = LabelData + "." + k
+		}
+		if i := typedIndex(typed, k); 0 <= i {
+			if err, ok := typed[i].Interface.(error); ErrorType == typed[i].Type && (!ok || nil == err) {
+				writeLogfmtKey(logLine, key)
+				logLine.WriteByte('=')
+				continue
+			}
+			text = typed[i].appendText(text[:0])
+			writeLogfmt(logLine, key, string(text))
+			continue
+		}
+		if nil == fields[k] {
+			writeLogfmtKey(logLine, key)
+			logLine.WriteByte('=')
+			continue
+		}
+		writeLogfmt(logLine, key, logfmtText(fields[k]))
+	}
+}
+
+// reservedKeys returns the keys used for default fields.
+func (f *LogfmtFormatter) reservedKeys() map[string]bool {
+	reserved := map[string]bool{}
+	for _, label := range []FieldLabel{
+		LabelCaller,
+		LabelError,
+		LabelHost,
+		LabelLevel,
+		LabelMsg,
+		LabelTime,
+		LabelTrace,
+	} {
+		reserved[f.FieldMap.resolve(label)] = true
+	}
+	return reserved
+}
+
+// flattenLogfmt adds fields to flat, nested maps are added with dotted keys.
+func flattenLogfmt(flat map[string]interface{}, prefix string, fields map[string]interface{}) {
+	for k, v := range fields {
+		key := k
+		if "" != prefix {
+			key = prefix + "." + k
+		}
+		switch tv := v.(type) {
+		case Fields:
+			flattenLogfmt(flat, key, tv)
+		case map[string]interface{}:
+			flattenLogfmt(flat, key, tv)
+		case map[string]string:
+			for sk, sv := range tv {
+				flat[key+"."+sk] = sv
+			}
+		default:
+			flat[key] = v
+		}
+	}
+}
+
+// logfmtText returns the text written for a value. Values without a natural
+// text form are JSON encoded.
+func logfmtText(v interface{}) string {
+	switch tv := v.(type) {
+	case string:
+		return tv
+	case []byte:
+		return string(tv)
+	case json.Number:
+		return string(tv)
+	case bool:
+		return strconv.FormatBool(tv)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return fmt.Sprintf("%d", tv)
+	case float32:
+		return strconv.FormatFloat(float64(tv), 'g', -1, 32)
+	case float64:
+		return strconv.FormatFloat(tv, 'g', -1, 64)
+	case time.Time:
+		return tv.Format(time.RFC3339Nano)
+	case error:
+		return tv.Error()
+	case fmt.Stringer:
+		return tv.String()
+	}
+	byts, err := json.Marshal(v)
+	if nil != err {
+		return fmt.Sprintf("%+v", v)
+	}
+	return string(byts)
+}
+
+// writeLogfmt writes a space separated key/value pair.
+func writeLogfmt(buf *bytes.Buffer, key, value string) {
+	writeLogfmtKey(buf, key)
+	buf.WriteByte('=')
+	if !logfmtNeedsQuotes(value) {
+		buf.WriteString(value)
+		return
+	}
+	buf.WriteByte('"')
+	for i := 0; i < len(value); {
+		c := value[i]
+		if c < utf8.RuneSelf {
+			switch c {
+			case '"', '\\':
+				buf.WriteByte('\\')
+				buf.WriteByte(c)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\t':
+				buf.WriteString(`\t`)
+			default:
+				if c < ' ' || 0x7f == c {
+					fmt.Fprintf(buf, `\u%04x`, c)
+				} else {
+					buf.WriteByte(c)
+				}
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(value[i:])
+		if utf8.RuneError == r && 1 == size {
+			buf.WriteString(`\ufffd`)
+		} else {
+			buf.WriteString(value[i : i+size])
+		}
+		i += size
+	}
+	buf.WriteByte('"')
+}
+
+// writeLogfmtKey writes a key, preceded by a space if it isn't the first
+// pair. Characters that are not valid in a key are replaced with an
+// underscore.
+func writeLogfmtKey(buf *bytes.Buffer, key string) {
+	if 0 < buf.Len() {
+		buf.WriteByte(' ')
+	}
+	if "" == key {
+		buf.WriteByte('_')
+		return
+	}
+	for i := 0; i < len(key); {
+		r, size := utf8.DecodeRuneInString(key[i:])
+		if r <= ' ' || '=' == r || '"' == r || 0x7f == r || (utf8.RuneError == r && 1 == size) {
+			buf.WriteByte('_')
+		} else {
+			buf.WriteString(key[i : i+size])
+		}
+		i += size
+	}
+}
+
+// logfmtNeedsQuotes reports whether a value must be quoted.
+func logfmtNeedsQuotes(value string) bool {
+	if "" == value {
+		return true
+	}
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; {
+		case c <= ' ', '=' == c, '"' == c, '\\' == c, 0x7f == c:
+			return true
+		}
+	}
+	return !utf8.ValidString(value)
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogfmtFormatting(t *testing.T) {
+	entry := NewEntry(New())
+	entry.Time = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry.Level = InfoLevel
+	entry.Message = "hello world"
+	entry.Data = Fields{
+		"a":        "plain",
+		"b":        `say "hi"`,
+		"bad key=": "v",
+		"c":        "",
+		"d":        nil,
+		"n":        3,
+		"nested":   Fields{"x": 1, "y": map[string]interface{}{"z": true}},
+	}
+	entry = entry.With(Int("count", 2), Duration("elapsed", time.Second))
+
+	f := &LogfmtFormatter{DisableCaller: true, DisableHostname: true}
+	b, err := f.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, `time=2020-01-02T03:04:05.000Z level=info msg="hello world"`+
+		` data.a=plain data.b="say \"hi\"" data.bad_key_=v data.c="" data.count=2`+
+		` data.d= data.elapsed=1s data.n=3 data.nested.x=1 data.nested.y.z=true`+"\n", string(b))
+}
+
+func TestLogfmtQuoting(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected string
+	}{
+		{`foo`, `k=foo`},
+		{`foo bar`, `k="foo bar"`},
+		{``, `k=""`},
+		{`a=b`, `k="a=b"`},
+		{`ba"r`, `k="ba\"r"`},
+		{`c:\tmp`, `k="c:\\tmp"`},
+		{"two\nlines\t", `k="two\nlines\t"`},
+		{"bell\x07", `k="bell\u0007"`},
+		{`héllo`, `k=héllo`},
+		{"bad\xffutf8", `k="bad\ufffdutf8"`},
+		{`<a href="x">`, `k="<a href=\"x\">"`},
+	}
+	for _, tc := range testCases {
+		buf := &bytes.Buffer{}
+		writeLogfmt(buf, "k", tc.value)
+		assert.Equal(t, tc.expected, buf.String(), "value %q", tc.value)
+	}
+
+	buf := &bytes.Buffer{}
+	writeLogfmtKey(buf, `a b="c"`)
+	writeLogfmtKey(buf, "")
+	assert.Equal(t, `a_b__c_ _`, buf.String())
+}
+
+func TestLogfmtRoundTrip(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := New()
+	logger.Out = buf
+	logger.Formatter = &LogfmtFormatter{EnableTrace: true}
+
+	logger.
+		WithFields(Fields{
+			"quote":  `say "hi"`,
+			"multi":  "a\nb",
+			"empty":  "",
+			"nil":    nil,
+			"nested": Fields{"id": 42},
+		}).
+		With(String("user", "alice")).
+		WithError(errors.New("failed: disk full")).
+		Warn("request failed")
+
+	record, err := ParseLogfmt(buf.Bytes())
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.True(t, record.HasLevel)
+	assert.Equal(t, WarnLevel, record.Level)
+	assert.Equal(t, "request failed", record.Message)
+	assert.Equal(t, "failed: disk full", record.Err.Error())
+	assert.False(t, record.Time.IsZero())
+	assert.NotEmpty(t, record.Host)
+	assert.Contains(t, record.Caller, "logfmt_formatter_test.go")
+	if assert.NotEmpty(t, record.Trace) {
+		assert.Contains(t, record.Trace[0], "TestLogfmtRoundTrip")
+	}
+	assert.Equal(t, Fields{
+		"quote":     `say "hi"`,
+		"multi":     "a\nb",
+		"empty":     "",
+		"nil":       nil,
+		"nested.id": "42",
+		"user":      "alice",
+	}, record.Data)
+}
+
+func TestLogfmtFieldMap(t *testing.T) {
+	f := &LogfmtFormatter{
+		DisableCaller:   true,
+		DisableHostname: true,
+		FieldMap:        FieldMap{LabelData: "", LabelMsg: "message"},
+		TimestampFormat: time.RFC1123,
+	}
+	entry := NewEntry(New())
+	entry.Time = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	entry.Level = ErrorLevel
+	entry.Message = "boom"
+	entry.Data = Fields{"level": "mine", "user": "alice"}
+
+	b, err := f.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, `time="Thu, 02 Jan 2020 03:04:05 UTC" level=error message=boom data.level=mine user=alice`+"\n", string(b))
+
+	record, err := f.Parse(b)
+	if assert.Nil(t, err) {
+		assert.Equal(t, entry.Time, record.Time.UTC())
+		assert.Equal(t, ErrorLevel, record.Level)
+		assert.Equal(t, "boom", record.Message)
+		assert.Equal(t, Fields{"level": "mine", "user": "alice"}, record.Data)
+	}
+}
+
+func TestParseLogfmt(t *testing.T) {
+	record, err := ParseLogfmt([]byte(`level=debug  msg="a b" flag data.k=v other= data.q="x y"`))
+	if assert.Nil(t, err) {
+		assert.Equal(t, DebugLevel, record.Level)
+		assert.Equal(t, "a b", record.Message)
+		assert.True(t, record.Time.IsZero())
+		assert.Nil(t, record.Err)
+		assert.Equal(t, Fields{"flag": true, "k": "v", "other": nil, "q": "x y"}, record.Data)
+	}
+
+	for _, line := range []string{
+		`msg="unterminated`,
+		`k"ey=v`,
+		`k=v"`,
+		`k=a=b`,
+		`k="a"b`,
+		`=v`,
+		`level=loud`,
+		`time=yesterday`,
+	} {
+		_, err := ParseLogfmt([]byte(line))
+		if assert.NotNil(t, err, line) {
+			assert.True(t, strings.HasPrefix(err.Error(), "logfmt: "), err.Error())
+		}
+	}
+}
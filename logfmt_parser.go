@@ -0,0 +1,182 @@
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// LogfmtRecord is a log entry decoded from `LogfmtFormatter` output.
+type LogfmtRecord struct {
+	// Caller is the caller, if it was written.
+	Caller string
+
+	// Data holds the data fields with the data prefix removed. Nested maps
+	// keep their dotted keys. Values are strings, except that an empty
+	// unquoted value is nil and a key without a value is true.
+	Data Fields
+
+	// Err is the entry's error message as an error, or nil.
+	Err error
+
+	// Host is the hostname, if it was written.
+	Host string
+
+	// HasLevel reports whether the line has a level.
+	HasLevel bool
+
+	// Level is the entry's level. It is the zero Level, which is
+	// `FatalLevel`, if the line has no level, check HasLevel.
+	Level stdLogger.Level
+
+	// Message is the entry's message.
+	Message string
+
+	// Time is the entry's time. It is the zero time if the line has no
+	// timestamp.
+	Time time.Time
+
+	// Trace holds the stack frames, if they were written.
+	Trace []string
+}
+
+// ParseLogfmt decodes a line written by a `LogfmtFormatter` with the default
+// settings.
+func ParseLogfmt(line []byte) (*LogfmtRecord, error) {
+	return (&LogfmtFormatter{}).Parse(line)
+}
+
+// Parse decodes a line written by the formatter. The formatter's `FieldMap`
+// and `TimestampFormat` are used to find the default fields.
+func (f *LogfmtFormatter) Parse(line []byte) (*LogfmtRecord, error) {
+	pairs, err := splitLogfmt(line)
+	if nil != err {
+		return nil, err
+	}
+
+	format := f.TimestampFormat
+	if "" == format {
+		format = defaultTimestampFormat
+	}
+	dataPrefix := ""
+	if label := f.FieldMap.resolve(LabelData); "" != label {
+		dataPrefix = label + "."
+	}
+	reserved := f.reservedKeys()
+
+	record := &LogfmtRecord{Data: Fields{}}
+	for _, pair := range pairs {
+		text, _ := pair.value.(string)
+		switch pair.key {
+		case f.FieldMap.resolve(LabelTime):
+			if record.Time, err = time.Parse(format, text); nil != err {
+				return nil, fmt.Errorf("logfmt: invalid %s value %q: %v", pair.key, text, err)
+			}
+			continue
+		case f.FieldMap.resolve(LabelLevel):
+			if record.Level, err = ParseLevel(text); nil != err {
+				return nil, fmt.Errorf("logfmt: invalid %s value %q: %v", pair.key, text, err)
+			}
+			record.HasLevel = true
+			continue
+		case f.FieldMap.resolve(LabelMsg):
+			record.Message = text
+			continue
+		case f.FieldMap.resolve(LabelError):
+			record.Err = errors.New(text)
+			continue
+		case f.FieldMap.resolve(LabelCaller):
+			record.Caller = text
+			continue
+		case f.FieldMap.resolve(LabelHost):
+			record.Host = text
+			continue
+		case f.FieldMap.resolve(LabelTrace):
+			record.Trace = strings.Split(text, "\n")
+			continue
+		}
+
+		key := pair.key
+		if "" != dataPrefix {
+			key = strings.TrimPrefix(key, dataPrefix)
+		} else if k := strings.TrimPrefix(key, LabelData+"."); reserved[k] {
+			key = k
+		}
+		record.Data[key] = pair.value
+	}
+	return record, nil
+}
+
+type logfmtPair struct {
+	key   string
+	value interface{}
+}
+
+// splitLogfmt splits a line into key/value pairs.
+func splitLogfmt(line []byte) ([]logfmtPair, error) {
+	pairs := []logfmtPair{}
+	for i := 0; i < len(line); {
+		if line[i] <= ' ' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] > ' ' && '=' != line[i] {
+			if '"' == line[i] {
+				return nil, fmt.Errorf("logfmt: unexpected '\"' in key at offset %d", i)
+			}
+			i++
+		}
+		if start == i {
+			return nil, fmt.Errorf("logfmt: missing key at offset %d", i)
+		}
+		pair := logfmtPair{key: string(line[start:i])}
+		if i == len(line) || '=' != line[i] {
+			// A key without a value.
+			pair.value = true
+			pairs = append(pairs, pair)
+			continue
+		}
+		i++
+
+		switch {
+		case i == len(line) || line[i] <= ' ':
+			pair.value = nil
+		case '"' == line[i]:
+			start = i
+			for i++; i < len(line) && '"' != line[i]; i++ {
+				if '\\' == line[i] {
+					i++
+				}
+			}
+			if i >= len(line) {
+				return nil, fmt.Errorf("logfmt: unterminated quoted value at offset %d", start)
+			}
+			i++
+			var value string
+			if err := json.Unmarshal(line[start:i], &value); nil != err {
+				return nil, fmt.Errorf("logfmt: invalid quoted value at offset %d: %v", start, err)
+			}
+			pair.value = value
+		default:
+			start = i
+			for i < len(line) && line[i] > ' ' {
+				if '"' == line[i] || '=' == line[i] {
+					return nil, fmt.Errorf("logfmt: unexpected %q in value at offset %d", line[i], i)
+				}
+				i++
+			}
+			pair.value = string(line[start:i])
+		}
+		if i < len(line) && line[i] > ' ' {
+			return nil, fmt.Errorf("logfmt: unexpected %q after value at offset %d", line[i], i)
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
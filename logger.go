@@ -28,14 +28,40 @@ type Logger struct {
 	// own that implements the `Formatter` interface, see the `README` or included
 	// formatters for examples.
 	Formatter Formatter
+	// Secrets, patterns and field keys redacted from this logger's output.
+	// Secrets added with the package-level `AddSecret` are redacted in
+	// addition to these.
+	Redactor *Redactor
 	// The logging level the logger should log at. This is typically (and defaults
 	// to) `log.Info`, which allows Info(), Warn(), Error() and Fatal() to be
 	// logged.
 	Level stdLogger.Level
+	// Function called after a Fatal entry is logged, once the exit handlers
+	// have run. Defaults to `os.Exit`. Tests can set it to keep Fatal calls
+	// from terminating the test binary.
+	ExitFunc func(code int)
 	// Used to sync writing to the log. Locking is enabled by Default
 	mu MutexWrap
 	// Reusable empty entry
 	entryPool sync.Pool
+	// Registered context extractors, see AddContextExtractor
+	extractors atomic.Value
+	// Async output writer, see SetAsync
+	async atomic.Value
+	// Whether the async exit handler has been registered
+	asyncExit bool
+	// Entry sampler, see SetSampler
+	sampler atomic.Value
+	// Component level overrides, see SetComponentLevel
+	components atomic.Value
+	// Fields added to every entry, see SetBaseFields
+	base atomic.Value
+	// Logger a child created by Child writes through, nil for other loggers
+	parent *Logger
+	// Hook failure handler, see SetHookErrorHandler
+	hookErrorHandler atomic.Value
+	// Processors sorted by priority, see AddProcessor
+	processors atomic.Value
 }
 
 // MutexWrap contains the mutex lock.
@@ -81,15 +107,20 @@ func New() *Logger {
 		Formatter: new(TextFormatter),
 		Hooks:     make(LevelHooks),
 		Level:     InfoLevel,
+		Redactor:  NewRedactor(),
 	}
 }
 
 func (logger *Logger) newEntry() *Entry {
 	entry, ok := logger.entryPool.Get().(*Entry)
-	if ok {
-		return entry
+	if !ok {
+		return NewEntry(logger)
 	}
-	return NewEntry(logger)
+	if 0 < len(logger.baseFields()) || 0 < len(entry.Data) {
+		entry.Data = logger.baseData()
+		entry.keys = logger.baseKeys()
+	}
+	return entry
 }
 
 func (logger *Logger) releaseEntry(entry *Entry) {
@@ -128,6 +159,15 @@ func (logger *Logger) WithTime(t time.Time) *Entry {
 	return entry.WithTime(t)
 }
 
+// Tracef logs a trace-level message using Printf.
+func (logger *Logger) Tracef(format string, args ...interface{}) {
+	if logger.level() >= TraceLevel {
+		entry := logger.newEntry()
+		entry.Tracef(format, args...)
+		logger.releaseEntry(entry)
+	}
+}
+
 // Debugf logs a debug-level message using Printf.
 func (logger *Logger) Debugf(format string, args ...interface{}) {
 	if logger.level() >= DebugLevel {
@@ -182,12 +222,10 @@ func (logger *Logger) Errorf(format string, args ...interface{}) {
 
 // Fatalf logs a fatal-level message using Printf.
 func (logger *Logger) Fatalf(format string, args ...interface{}) {
-	if logger.level() >= FatalLevel {
-		entry := logger.newEntry()
-		entry.Fatalf(format, args...)
-		logger.releaseEntry(entry)
-	}
-	Exit(1)
+	// The entry exits even if the level is disabled.
+	entry := logger.newEntry()
+	entry.Fatalf(format, args...)
+	logger.releaseEntry(entry)
 }
 
 // Panicf logs a panic-level message using Printf.
@@ -199,6 +237,15 @@ func (logger *Logger) Panicf(format string, args ...interface{}) {
 	}
 }
 
+// Trace logs a trace-level message using Println.
+func (logger *Logger) Trace(args ...interface{}) {
+	if logger.level() >= TraceLevel {
+		entry := logger.newEntry()
+		entry.Trace(args...)
+		logger.releaseEntry(entry)
+	}
+}
+
 // Debug logs a debug-level message using Println.
 func (logger *Logger) Debug(args ...interface{}) {
 	if logger.level() >= DebugLevel {
@@ -253,12 +300,10 @@ func (logger *Logger) Error(args ...interface{}) {
 
 // Fatal logs a fatal-level message using Println.
 func (logger *Logger) Fatal(args ...interface{}) {
-	if logger.level() >= FatalLevel {
-		entry := logger.newEntry()
-		entry.Fatal(args...)
-		logger.releaseEntry(entry)
-	}
-	Exit(1)
+	// The entry exits even if the level is disabled.
+	entry := logger.newEntry()
+	entry.Fatal(args...)
+	logger.releaseEntry(entry)
 }
 
 // Panic logs a panic-level message using Println.
@@ -270,6 +315,15 @@ func (logger *Logger) Panic(args ...interface{}) {
 	}
 }
 
+// Traceln logs a trace-level message using Println.
+func (logger *Logger) Traceln(args ...interface{}) {
+	if logger.level() >= TraceLevel {
+		entry := logger.newEntry()
+		entry.Traceln(args...)
+		logger.releaseEntry(entry)
+	}
+}
+
 // Debugln logs a debug-level message using Println.
 func (logger *Logger) Debugln(args ...interface{}) {
 	if logger.level() >= DebugLevel {
@@ -324,12 +378,10 @@ func (logger *Logger) Errorln(args ...interface{}) {
 
 // Fatalln logs a fatal-level message using Println.
 func (logger *Logger) Fatalln(args ...interface{}) {
-	if logger.level() >= FatalLevel {
-		entry := logger.newEntry()
-		entry.Fatalln(args...)
-		logger.releaseEntry(entry)
-	}
-	Exit(1)
+	// The entry exits even if the level is disabled.
+	entry := logger.newEntry()
+	entry.Fatalln(args...)
+	logger.releaseEntry(entry)
 }
 
 // Panicln logs a panic-level message using Println.
@@ -341,24 +393,54 @@ func (logger *Logger) Panicln(args ...interface{}) {
 	}
 }
 
+// Log logs a message at any level, including custom levels, see
+// `Entry.Log`.
+func (logger *Logger) Log(level stdLogger.Level, args ...interface{}) {
+	entry := logger.newEntry()
+	entry.Log(level, args...)
+	logger.releaseEntry(entry)
+}
+
+// Logf logs a message at any level using Printf, see `Entry.Log`.
+func (logger *Logger) Logf(level stdLogger.Level, format string, args ...interface{}) {
+	entry := logger.newEntry()
+	entry.Logf(level, format, args...)
+	logger.releaseEntry(entry)
+}
+
+// Logln logs a message at any level using Println, see `Entry.Log`.
+func (logger *Logger) Logln(level stdLogger.Level, args ...interface{}) {
+	entry := logger.newEntry()
+	entry.Logln(level, args...)
+	logger.releaseEntry(entry)
+}
+
 // SetNoLock disables locking. When file is opened with appending mode, it's
 // safe to write concurrently to a file (within 4k message on Linux). In these
 // cases user can choose to disable the lock.
 func (logger *Logger) SetNoLock() {
+	logger = logger.root()
 	logger.mu.Disable()
 }
 
 func (logger *Logger) level() stdLogger.Level {
-	return stdLogger.Level(atomic.LoadUint32((*uint32)(&logger.Level)))
+	return stdLogger.Level(atomic.LoadUint32((*uint32)(&logger.root().Level)))
 }
 
 // SetLevel sets the minimum logging level.
 func (logger *Logger) SetLevel(level stdLogger.Level) {
+	logger = logger.root()
 	atomic.StoreUint32((*uint32)(&logger.Level), uint32(level))
 }
 
+// GetLevel returns the logger level.
+func (logger *Logger) GetLevel() stdLogger.Level {
+	return logger.level()
+}
+
 // SetOutput sets the logger output writer.
 func (logger *Logger) SetOutput(out io.Writer) {
+	logger = logger.root()
 	logger.mu.Lock()
 	defer logger.mu.Unlock()
 	logger.Out = out
@@ -366,7 +448,19 @@ func (logger *Logger) SetOutput(out io.Writer) {
 
 // AddHook adds a hook to the stack.
 func (logger *Logger) AddHook(hook Hook) {
+	logger = logger.root()
 	logger.mu.Lock()
 	defer logger.mu.Unlock()
 	logger.Hooks.Add(hook)
 }
+
+// exit runs the exit handlers and calls `ExitFunc`.
+func (logger *Logger) exit(code int) {
+	exitFunc := logger.root().ExitFunc
+	if nil == exitFunc {
+		Exit(code)
+		return
+	}
+	runHandlers()
+	exitFunc(code)
+}
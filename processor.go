@@ -0,0 +1,96 @@
+package log
+
+import (
+	"fmt"
+)
+
+// Processor is a hook that runs before an entry is redacted, its hooks are
+// fired and it is formatted. Processors run in priority order and can change
+// the entry's `Data`, `Message` and `Level`, e.g. to enrich entries, or
+// return false to drop the entry. `Data` is a copy the processor may modify.
+//
+// Processors run for every entry that passes the level check, check
+// `entry.Level` to limit a processor to some levels. Changing the level does
+// not change whether a Panic or Fatal call panics or exits.
+type Processor interface {
+	Process(entry *Entry) bool
+}
+
+// ProcessorFunc is a function that implements Processor.
+type ProcessorFunc func(entry *Entry) bool
+
+// Process implements Processor.
+func (fn ProcessorFunc) Process(entry *Entry) bool {
+	return fn(entry)
+}
+
+// AddProcessor adds a processor that runs before processors with a lower
+// priority, processors with the same priority run in the order they were
+// added. The returned handle removes the processor, see `RemoveHook`.
+func (logger *Logger) AddProcessor(processor Processor, priority int) *HookHandle {
+	logger = logger.root()
+	handle := &HookHandle{Processor: processor, Priority: priority}
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	current := logger.getProcessors()
+	i := 0
+	for i < len(current) && current[i].Priority >= priority {
+		i++
+	}
+	processors := make([]*HookHandle, 0, len(current)+1)
+	processors = append(processors, current[:i]...)
+	processors = append(processors, handle)
+	logger.processors.Store(append(processors, current[i:]...))
+	return handle
+}
+
+// removeProcessor removes a processor, it must be called with the lock held.
+func (logger *Logger) removeProcessor(handle *HookHandle) {
+	current := logger.getProcessors()
+	processors := make([]*HookHandle, 0, len(current))
+	for _, p := range current {
+		if p != handle {
+			processors = append(processors, p)
+		}
+	}
+	if len(processors) < len(current) {
+		logger.processors.Store(processors)
+	}
+}
+
+func (logger *Logger) getProcessors() []*HookHandle {
+	processors, _ := logger.processors.Load().([]*HookHandle)
+	return processors
+}
+
+// process runs the logger's processors and reports whether the entry should
+// be logged. Panics are recovered and reported to the logger's
+// `HookErrorHandler`, the entry is logged as if the processor returned true.
+func (entry *Entry) process() bool {
+	processors := entry.Logger.getProcessors()
+	if 0 == len(processors) {
+		return true
+	}
+	entry.Data = Fields{}.merge(entry.Data)
+	for _, handle := range processors {
+		keep, err := runProcessor(handle.Processor, entry)
+		if nil != err {
+			entry.Logger.handleHookError(entry, HookErrors{err})
+		}
+		if !keep {
+			return false
+		}
+	}
+	return true
+}
+
+func runProcessor(processor Processor, entry *Entry) (keep bool, hookErr *HookError) {
+	defer func() {
+		if r := recover(); nil != r {
+			keep = true
+			hookErr = &HookError{Err: fmt.Errorf("processor %T: panic: %v", processor, r), Panic: r}
+		}
+	}()
+	return processor.Process(entry), nil
+}
@@ -0,0 +1,127 @@
+package log
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessorModifiesEntry(t *testing.T) {
+	hook := &RecordHook{}
+	fields := Fields{"user": "alice"}
+
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddHook(hook)
+		log.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+			entry.Data["region"] = "eu"
+			entry.Message = "[api] " + entry.Message
+			return true
+		}), 0)
+		log.WithFields(fields).Warn("test")
+	}, func(data logData) {
+		assert.Equal(t, "[api] test", data.Message)
+		assert.Equal(t, "eu", data.Data["region"])
+		assert.Equal(t, "warn", data.Level)
+	})
+
+	// The entry's own fields are not modified.
+	assert.Equal(t, Fields{"user": "alice"}, fields)
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, "[api] test", hook.Entries[0].Message)
+		assert.Equal(t, "eu", hook.Entries[0].Data["region"])
+	}
+}
+
+func TestProcessorChangesLevel(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+			entry.Level = ErrorLevel
+			return true
+		}), 0)
+		log.Info("test")
+	}, func(data logData) {
+		assert.Equal(t, "error", data.Level)
+	})
+}
+
+func TestProcessorVeto(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	hook := &RecordHook{}
+	logger.AddHook(hook)
+
+	handle := logger.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+		return "/healthz" != entry.Data["path"]
+	}), 0)
+
+	logger.WithField("path", "/healthz").Info("request")
+	assert.Empty(t, buffer.String())
+	assert.Empty(t, hook.Entries)
+
+	logger.WithField("path", "/users").Info("request")
+	assert.Contains(t, buffer.String(), "/users")
+
+	// Vetoed Panic calls still panic.
+	assert.Panics(t, func() {
+		logger.WithField("path", "/healthz").Panic("request")
+	})
+
+	logger.RemoveHook(handle)
+	buffer.Reset()
+	logger.WithField("path", "/healthz").Info("request")
+	assert.Contains(t, buffer.String(), "/healthz")
+}
+
+func TestProcessorPriority(t *testing.T) {
+	order := []string{}
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	add := func(name string, priority int) {
+		logger.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+			order = append(order, name)
+			return true
+		}), priority)
+	}
+	add("low", -1)
+	add("high", 1)
+	add("zero", 0)
+
+	logger.Info("test")
+	assert.Equal(t, []string{"high", "zero", "low"}, order)
+}
+
+func TestProcessorRunsBeforeRedaction(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Redactor.AddKey("token")
+	logger.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+		entry.Data["token"] = "s3cr3t"
+		return true
+	}), 0)
+
+	logger.Info("test")
+	assert.NotContains(t, buffer.String(), "s3cr3t")
+	assert.Contains(t, buffer.String(), Redacted)
+}
+
+func TestProcessorPanic(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	var hookErr error
+	logger.SetHookErrorHandler(func(entry *Entry, err error) {
+		hookErr = err
+	})
+	logger.AddProcessor(ProcessorFunc(func(entry *Entry) bool {
+		panic("broken")
+	}), 0)
+
+	logger.Info("test")
+	assert.Contains(t, buffer.String(), "test")
+	if errs, ok := hookErr.(HookErrors); assert.True(t, ok) {
+		assert.Equal(t, "broken", errs[0].Panic)
+	}
+}
@@ -0,0 +1,339 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bdlm/log/v2"
+)
+
+// labels holds the key names of the default fields.
+type labels struct {
+	caller, data, err, host, level, msg, time, trace string
+}
+
+func newLabels(fieldMap log.FieldMap) labels {
+	resolve := func(label log.FieldLabel) string {
+		if name, ok := fieldMap[label]; ok {
+			return name
+		}
+		return string(label)
+	}
+	return labels{
+		caller: resolve(log.LabelCaller),
+		data:   resolve(log.LabelData),
+		err:    resolve(log.LabelError),
+		host:   resolve(log.LabelHost),
+		level:  resolve(log.LabelLevel),
+		msg:    resolve(log.LabelMsg),
+		time:   resolve(log.LabelTime),
+		trace:  resolve(log.LabelTrace),
+	}
+}
+
+// dataKey returns a data field's key without the data prefix.
+func (l labels) dataKey(key string) string {
+	if "" == l.data {
+		return key
+	}
+	return strings.TrimPrefix(key, l.data+".")
+}
+
+// detect returns the format of a line. Text lines always start with a quoted
+// time or level, logfmt lines start with a key and std lines with a
+// timestamp or the message.
+func detect(line []byte, fieldMap log.FieldMap) Format {
+	line = trimSpace(line)
+	if bytes.HasPrefix(line, []byte("{")) {
+		return FormatJSON
+	}
+	l := newLabels(fieldMap)
+	if bytes.HasPrefix(line, []byte(l.time+`="`)) || bytes.HasPrefix(line, []byte(l.level+`="`)) {
+		return FormatText
+	}
+	if isKey(line) {
+		return FormatLogfmt
+	}
+	return FormatStd
+}
+
+// isKey reports whether s starts with a key followed by "=".
+func isKey(s []byte) bool {
+	for i, c := range s {
+		switch {
+		case '=' == c:
+			return 0 < i
+		case c <= ' ', '"' == c:
+			return false
+		}
+	}
+	return false
+}
+
+func parseJSON(line []byte, opts Options) (*Record, error) {
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	values := map[string]interface{}{}
+	if err := decoder.Decode(&values); nil != err {
+		return nil, err
+	}
+
+	l := newLabels(opts.FieldMap)
+	record := newRecord()
+	for k, v := range values {
+		switch k {
+		case l.time:
+			if err := record.setTime(text(v), DefaultTimestampFormat, opts); nil != err {
+				return nil, fmt.Errorf("invalid %s: %v", k, err)
+			}
+		case l.level:
+			if err := record.setLevel(text(v)); nil != err {
+				return nil, err
+			}
+		case l.msg:
+			record.Message = text(v)
+		case l.err:
+			record.setError(text(v))
+		case l.caller:
+			record.Caller = text(v)
+		case l.host:
+			record.Host = text(v)
+		case l.trace:
+			frames, _ := v.([]interface{})
+			for _, frame := range frames {
+				record.Trace = append(record.Trace, text(frame))
+			}
+		case l.data:
+			if data, ok := v.(map[string]interface{}); ok {
+				for k, v := range data {
+					record.Data[k] = dataValue(v)
+				}
+				continue
+			}
+			record.Data[k] = v
+		default:
+			record.Data[k] = v
+		}
+	}
+	return record, nil
+}
+
+func parseText(line []byte, opts Options) (*Record, error) {
+	record := newRecord()
+	if err := parsePairs(record, trimSpace(line), DefaultTimestampFormat, opts); nil != err {
+		return nil, err
+	}
+	return record, nil
+}
+
+func parseStd(line []byte, opts Options) (*Record, error) {
+	record := newRecord()
+	rest := trimSpace(line)
+
+	format := DefaultStdTimestampFormat
+	if "" != opts.TimestampFormat {
+		format = opts.TimestampFormat
+	}
+	// Timestamps are optional, try the number of words the format has.
+	words := strings.Count(time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC).Format(format), " ") + 1
+	if end := wordsEnd(rest, words); 0 < end {
+		if err := record.setTime(string(rest[:end]), format, opts); nil == err {
+			rest = trimSpace(rest[end:])
+		}
+	}
+
+	l := newLabels(opts.FieldMap)
+	end := len(rest)
+	for i := 0; i < len(rest); i++ {
+		if (0 == i || ' ' == rest[i-1]) && l.isStdPair(rest[i:]) {
+			end = i
+			break
+		}
+	}
+	if record.Time.IsZero() && !bytes.Contains(rest[end:], []byte(l.level+`="`)) {
+		return nil, fmt.Errorf("no timestamp or level")
+	}
+	record.Message = string(trimSpace(rest[:end]))
+	if err := parsePairs(record, rest[end:], format, opts); nil != err {
+		return nil, err
+	}
+	return record, nil
+}
+
+// isStdPair reports whether s starts with a key written by the std
+// formatter after the message.
+func (l labels) isStdPair(s []byte) bool {
+	for _, key := range []string{l.level, l.err, l.caller, l.host} {
+		if bytes.HasPrefix(s, []byte(key+`="`)) {
+			return true
+		}
+	}
+	if bytes.HasPrefix(s, []byte("trace.")) {
+		return isKey(s)
+	}
+	if "" == l.data {
+		return isKey(s)
+	}
+	return bytes.HasPrefix(s, []byte(l.data+".")) && isKey(s)
+}
+
+// wordsEnd returns the end of the first n space separated words in s, or 0
+// if s has fewer words.
+func wordsEnd(s []byte, n int) int {
+	for i := 0; i < len(s); i++ {
+		if ' ' == s[i] {
+			n--
+			if 0 == n {
+				return i
+			}
+		}
+	}
+	if 1 == n {
+		return len(s)
+	}
+	return 0
+}
+
+// parsePairs parses the key/value pairs written by the text and std
+// formatters. The message and data fields are JSON encoded, the other
+// default fields are quoted as-is.
+func parsePairs(record *Record, s []byte, timestampFormat string, opts Options) error {
+	l := newLabels(opts.FieldMap)
+	for i := 0; i < len(s); {
+		if s[i] <= ' ' {
+			i++
+			continue
+		}
+		eq := bytes.IndexByte(s[i:], '=')
+		if 0 >= eq || !isKey(s[i:]) {
+			return fmt.Errorf("expected key=value at offset %d", i)
+		}
+		key := string(s[i : i+eq])
+		i += eq + 1
+
+		switch {
+		case l.time == key, l.level == key, l.err == key, l.caller == key, l.host == key,
+			strings.HasPrefix(key, "trace."):
+			value, n := rawValue(s[i:])
+			i += n
+			switch {
+			case l.time == key:
+				if err := record.setTime(value, timestampFormat, opts); nil != err {
+					return fmt.Errorf("invalid %s: %v", key, err)
+				}
+			case l.level == key:
+				if err := record.setLevel(value); nil != err {
+					return err
+				}
+			case l.err == key:
+				record.setError(value)
+			case l.caller == key:
+				record.Caller = value
+			case l.host == key:
+				record.Host = value
+			default:
+				record.Trace = append(record.Trace, value)
+			}
+		case l.msg == key:
+			value, n := jsonValue(s[i:])
+			i += n
+			record.Message = text(value)
+		default:
+			value, n := jsonValue(s[i:])
+			i += n
+			record.Data[l.dataKey(key)] = dataValue(value)
+		}
+	}
+	return nil
+}
+
+// rawValue returns a value quoted without escaping and its length. The
+// closing quote is the first one followed by the end of the line or by
+// another key.
+func rawValue(s []byte) (string, int) {
+	if 0 == len(s) || '"' != s[0] {
+		n := bytes.IndexByte(s, ' ')
+		if 0 > n {
+			n = len(s)
+		}
+		return string(s[:n]), n
+	}
+	for i := 1; i < len(s); i++ {
+		if '"' == s[i] && (i+1 == len(s) || (' ' == s[i+1] && isKey(s[i+2:]))) {
+			return string(s[1:i]), i + 1
+		}
+	}
+	return string(s[1:]), len(s)
+}
+
+// jsonValue returns a JSON encoded value and its length. Values that aren't
+// valid JSON are returned as a string up to the next space.
+func jsonValue(s []byte) (interface{}, int) {
+	decoder := json.NewDecoder(bytes.NewReader(s))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); nil == err {
+		n := int(decoder.InputOffset())
+		if n == len(s) || ' ' == s[n] {
+			return value, n
+		}
+	}
+	n := bytes.IndexByte(s, ' ')
+	if 0 > n {
+		n = len(s)
+	}
+	return string(s[:n]), n
+}
+
+// dataValue undoes the quoting the formatters apply to string data values.
+func dataValue(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if unquoted, err := strconv.Unquote(`"` + s + `"`); nil == err {
+			return unquoted
+		}
+	}
+	return v
+}
+
+// text returns a decoded value as text.
+func text(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tv
+	case json.Number:
+		return string(tv)
+	}
+	byts, err := json.Marshal(v)
+	if nil != err {
+		return fmt.Sprint(v)
+	}
+	return string(byts)
+}
+
+func parseLogfmt(line []byte, opts Options) (*Record, error) {
+	formatter := &log.LogfmtFormatter{
+		FieldMap:        opts.FieldMap,
+		TimestampFormat: opts.TimestampFormat,
+	}
+	parsed, err := formatter.Parse(line)
+	if nil != err {
+		return nil, err
+	}
+	return &Record{
+		Caller:   parsed.Caller,
+		Data:     parsed.Data,
+		Err:      parsed.Err,
+		Host:     parsed.Host,
+		HasLevel: parsed.HasLevel,
+		Level:    parsed.Level,
+		Message:  parsed.Message,
+		Time:     parsed.Time,
+		Trace:    parsed.Trace,
+	}, nil
+}
@@ -0,0 +1,288 @@
+/*
+Package reader decodes the output of the bdlm/log formatters back into
+structured records, for log processing tools and for assertions on real
+output in integration tests.
+
+	dec := reader.NewDecoder(os.Stdin, reader.Options{})
+	for {
+		record, err := dec.Decode()
+		if io.EOF == err {
+			break
+		}
+		if _, ok := err.(*reader.SyntaxError); ok {
+			continue // not a log line
+		}
+		if nil != err {
+			panic(err)
+		}
+		fmt.Println(record.Level, record.Message, record.Data)
+	}
+
+`JSONFormatter`, `TextFormatter`, `StdFormatter` and `LogfmtFormatter`
+output is supported, TTY output is not. The format of each line is detected
+unless one is set in the options. Options must match the formatter's
+`FieldMap` and `TimestampFormat` for the default fields to be recognized.
+*/
+package reader
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// Format identifies the formatter that wrote a line.
+type Format int
+
+// Supported formats.
+const (
+	// FormatAuto detects the format of each line.
+	FormatAuto Format = iota
+	// FormatJSON is `log.JSONFormatter` output.
+	FormatJSON
+	// FormatText is `log.TextFormatter` output.
+	FormatText
+	// FormatStd is `log.StdFormatter` output.
+	FormatStd
+	// FormatLogfmt is `log.LogfmtFormatter` output.
+	FormatLogfmt
+)
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	switch f {
+	case FormatAuto:
+		return "auto"
+	case FormatJSON:
+		return "json"
+	case FormatText:
+		return "text"
+	case FormatStd:
+		return "std"
+	case FormatLogfmt:
+		return "logfmt"
+	}
+	return "unknown"
+}
+
+// Default timestamp formats of the formatters.
+const (
+	DefaultTimestampFormat    = log.RFC3339Milli
+	DefaultStdTimestampFormat = "2006/01/02 15:04:05"
+)
+
+// Options configures decoding.
+type Options struct {
+	// Format is the format of the lines, FormatAuto detects it per line.
+	Format Format
+
+	// FieldMap is the formatter's key names for the default fields.
+	FieldMap log.FieldMap
+
+	// TimestampFormat is the formatter's timestamp format. The formatter's
+	// default is used if empty.
+	TimestampFormat string
+
+	// Location is used for timestamps without a time zone, time.Local if
+	// nil.
+	Location *time.Location
+}
+
+// Record is a decoded log line.
+type Record struct {
+	// Caller is the caller, if it was written.
+	Caller string
+
+	// Data holds the data fields with the data prefix removed. Values are
+	// decoded from JSON where the formatter wrote JSON, numbers are
+	// json.Number. Nested logfmt fields keep their dotted keys.
+	Data log.Fields
+
+	// Err is the entry's error message as an error, or nil.
+	Err error
+
+	// Format is the format the line was decoded as.
+	Format Format
+
+	// Host is the hostname, if it was written.
+	Host string
+
+	// HasLevel reports whether the line has a level.
+	HasLevel bool
+
+	// Level is the entry's level. It is the zero Level, which is
+	// `log.FatalLevel`, if the line has no level, check HasLevel.
+	Level stdLogger.Level
+
+	// Message is the entry's message.
+	Message string
+
+	// Raw is the line, without the trailing newline.
+	Raw []byte
+
+	// Time is the entry's time. It is the zero time if the line has no
+	// timestamp.
+	Time time.Time
+
+	// Trace holds the stack frames, if they were written.
+	Trace []string
+}
+
+// SyntaxError is returned for a line that can't be decoded. Decoding can
+// continue with the next line.
+type SyntaxError struct {
+	// Line is the 1-based line number, 0 for lines passed to Parse.
+	Line int
+	// Raw is the line.
+	Raw []byte
+	// Err is the cause.
+	Err error
+}
+
+// Error implements error.
+func (e *SyntaxError) Error() string {
+	if 0 == e.Line {
+		return fmt.Sprintf("reader: %v", e.Err)
+	}
+	return fmt.Sprintf("reader: line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// MaxLineSize is the longest line a Decoder reads.
+const MaxLineSize = 1 << 20
+
+// Decoder reads records from a stream of formatter output, one per line.
+type Decoder struct {
+	opts    Options
+	scanner *bufio.Scanner
+	line    int
+}
+
+// NewDecoder returns a decoder that reads from r.
+func NewDecoder(r io.Reader, opts Options) *Decoder {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxLineSize)
+	return &Decoder{opts: opts, scanner: scanner}
+}
+
+// Decode returns the next record. Blank lines are skipped. It returns io.EOF
+// at the end of the stream and a *SyntaxError for a line that can't be
+// decoded, after which decoding can continue.
+func (dec *Decoder) Decode() (*Record, error) {
+	for dec.scanner.Scan() {
+		dec.line++
+		line := dec.scanner.Bytes()
+		if 0 == len(trimSpace(line)) {
+			continue
+		}
+		raw := make([]byte, len(line))
+		copy(raw, line)
+		record, err := parse(raw, dec.opts)
+		if nil != err {
+			return nil, &SyntaxError{Line: dec.line, Raw: raw, Err: err}
+		}
+		return record, nil
+	}
+	if err := dec.scanner.Err(); nil != err {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// Parse decodes a single line.
+func Parse(line []byte, opts Options) (*Record, error) {
+	record, err := parse(line, opts)
+	if nil != err {
+		return nil, &SyntaxError{Raw: line, Err: err}
+	}
+	return record, nil
+}
+
+func parse(line []byte, opts Options) (*Record, error) {
+	line = trimNewline(line)
+	format := opts.Format
+	if FormatAuto == format {
+		format = detect(line, opts.FieldMap)
+	}
+
+	var record *Record
+	var err error
+	switch format {
+	case FormatJSON:
+		record, err = parseJSON(line, opts)
+	case FormatText:
+		record, err = parseText(line, opts)
+	case FormatStd:
+		record, err = parseStd(line, opts)
+	case FormatLogfmt:
+		record, err = parseLogfmt(line, opts)
+	default:
+		err = fmt.Errorf("unknown format %d", format)
+	}
+	if nil != err {
+		return nil, err
+	}
+	record.Format = format
+	record.Raw = line
+	return record, nil
+}
+
+// newRecord returns a record for a line with empty data.
+func newRecord() *Record {
+	return &Record{Data: log.Fields{}}
+}
+
+// setLevel parses a level name into the record.
+func (r *Record) setLevel(level string) error {
+	var err error
+	r.Level, err = log.ParseLevel(level)
+	r.HasLevel = nil == err
+	return err
+}
+
+// setTime parses a timestamp into the record.
+func (r *Record) setTime(value, format string, opts Options) error {
+	if "" != opts.TimestampFormat {
+		format = opts.TimestampFormat
+	}
+	loc := opts.Location
+	if nil == loc {
+		loc = time.Local
+	}
+	var err error
+	r.Time, err = time.ParseInLocation(format, value, loc)
+	return err
+}
+
+// setError sets the record's error from its message.
+func (r *Record) setError(msg string) {
+	if "" != msg {
+		r.Err = errors.New(msg)
+	}
+}
+
+func trimNewline(line []byte) []byte {
+	for 0 < len(line) && ('\n' == line[len(line)-1] || '\r' == line[len(line)-1]) {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+func trimSpace(line []byte) []byte {
+	for 0 < len(line) && line[0] <= ' ' {
+		line = line[1:]
+	}
+	for 0 < len(line) && line[len(line)-1] <= ' ' {
+		line = line[:len(line)-1]
+	}
+	return line
+}
@@ -0,0 +1,165 @@
+package reader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// logLine logs a warning with a few fields through formatter and returns the
+// output.
+func logLine(formatter log.Formatter) []byte {
+	buf := &bytes.Buffer{}
+	logger := log.New()
+	logger.Out = buf
+	logger.Formatter = formatter
+	logger.
+		WithFields(log.Fields{
+			"quote": `say "hi" to c:\tmp "now"`,
+			"count": 3,
+			"user":  map[string]interface{}{"id": 42},
+		}).
+		With(log.String("unicode", "héllo")).
+		WithError(errors.New("disk full")).
+		Warn("request failed")
+	return buf.Bytes()
+}
+
+func TestFormats(t *testing.T) {
+	testCases := []struct {
+		format    Format
+		formatter log.Formatter
+		opts      Options
+	}{
+		{FormatJSON, &log.JSONFormatter{DisableTTY: true, EnableTrace: true}, Options{}},
+		{FormatText, &log.TextFormatter{DisableTTY: true, EnableTrace: true}, Options{}},
+		{FormatStd, &log.StdFormatter{EnableTrace: true}, Options{}},
+		{FormatLogfmt, &log.LogfmtFormatter{EnableTrace: true}, Options{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.format.String(), func(t *testing.T) {
+			line := logLine(tc.formatter)
+			record, err := Parse(line, tc.opts)
+			if !assert.Nil(t, err, string(line)) {
+				return
+			}
+			assert.Equal(t, tc.format, record.Format)
+			assert.True(t, record.HasLevel)
+			assert.Equal(t, log.WarnLevel, record.Level)
+			assert.Equal(t, "request failed", record.Message)
+			if assert.NotNil(t, record.Err) {
+				assert.Equal(t, "disk full", record.Err.Error())
+			}
+			assert.WithinDuration(t, time.Now(), record.Time, 2*time.Second)
+			assert.NotEmpty(t, record.Host)
+			assert.Contains(t, record.Caller, "reader_test.go")
+			if assert.NotEmpty(t, record.Trace) {
+				assert.Contains(t, record.Trace[0], "reader_test.go")
+			}
+			assert.Equal(t, `say "hi" to c:\tmp "now"`, record.Data["quote"])
+			assert.Equal(t, "héllo", record.Data["unicode"])
+			assert.Equal(t, "3", text(record.Data["count"]))
+			if FormatLogfmt == tc.format {
+				assert.Equal(t, "42", record.Data["user.id"])
+			} else {
+				assert.Equal(t, map[string]interface{}{"id": json.Number("42")}, record.Data["user"])
+			}
+			assert.Equal(t, strings.TrimSuffix(string(line), "\n"), string(record.Raw))
+		})
+	}
+}
+
+func TestFieldMapAndTimestampFormat(t *testing.T) {
+	fieldMap := log.FieldMap{
+		log.LabelData:  "fields",
+		log.LabelLevel: "severity",
+		log.LabelMsg:   "message",
+		log.LabelTime:  "ts",
+	}
+	opts := Options{FieldMap: fieldMap, TimestampFormat: time.RFC1123Z}
+
+	for _, formatter := range []log.Formatter{
+		&log.JSONFormatter{DisableTTY: true, FieldMap: fieldMap, TimestampFormat: time.RFC1123Z},
+		&log.TextFormatter{DisableTTY: true, FieldMap: fieldMap, TimestampFormat: time.RFC1123Z},
+		&log.StdFormatter{FieldMap: fieldMap, TimestampFormat: time.RFC1123Z},
+		&log.LogfmtFormatter{FieldMap: fieldMap, TimestampFormat: time.RFC1123Z},
+	} {
+		line := logLine(formatter)
+		record, err := Parse(line, opts)
+		if !assert.Nil(t, err, string(line)) {
+			continue
+		}
+		assert.Equal(t, log.WarnLevel, record.Level, string(line))
+		assert.Equal(t, "request failed", record.Message, string(line))
+		assert.WithinDuration(t, time.Now(), record.Time, 2*time.Second, string(line))
+		assert.Equal(t, `say "hi" to c:\tmp "now"`, record.Data["quote"], string(line))
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	var stream bytes.Buffer
+	stream.Write(logLine(&log.JSONFormatter{DisableTTY: true}))
+	stream.WriteString("\n")
+	stream.WriteString("panic: something else entirely\n")
+	stream.Write(logLine(&log.TextFormatter{DisableTTY: true}))
+	stream.WriteString(`{"broken":` + "\n")
+	stream.Write(logLine(&log.StdFormatter{}))
+
+	dec := NewDecoder(&stream, Options{})
+	formats := []Format{}
+	lines := []int{}
+	for {
+		record, err := dec.Decode()
+		if io.EOF == err {
+			break
+		}
+		if syntaxErr, ok := err.(*SyntaxError); ok {
+			lines = append(lines, syntaxErr.Line)
+			continue
+		}
+		if !assert.Nil(t, err) {
+			return
+		}
+		formats = append(formats, record.Format)
+	}
+	assert.Equal(t, []Format{FormatJSON, FormatText, FormatStd}, formats)
+	assert.Equal(t, []int{3, 5}, lines)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, line := range []string{
+		`{"level":"loud"}`,
+		`{"time":"yesterday"}`,
+		`level="info" time="yesterday"`,
+		`level="info" "oops"`,
+		`just a message`,
+		`k="unterminated`,
+	} {
+		_, err := Parse([]byte(line), Options{})
+		if assert.NotNil(t, err, line) {
+			_, ok := err.(*SyntaxError)
+			assert.True(t, ok, line)
+			assert.True(t, strings.HasPrefix(err.Error(), "reader: "), err.Error())
+		}
+	}
+}
+
+func TestParseWithoutLevel(t *testing.T) {
+	for _, line := range []string{
+		`{"user":"bob"}`,
+		`msg="hello" user="bob"`,
+	} {
+		record, err := Parse([]byte(line), Options{})
+		if assert.Nil(t, err, line) {
+			assert.False(t, record.HasLevel, line)
+			assert.True(t, record.Time.IsZero(), line)
+		}
+	}
+}
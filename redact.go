@@ -0,0 +1,448 @@
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Redactor removes secrets from log output. It supports literal secrets,
+// regular expressions and field keys whose values are always masked. All
+// literal secrets are matched in a single pass over the output, so the cost
+// of redaction does not grow with the number of secrets. Each pattern is
+// matched in its own pass, overlapping matches are redacted as one span.
+//
+// Each Logger has its own Redactor, secrets added to one logger do not affect
+// the output of another. Secrets added with the package-level `AddSecret` are
+// redacted from the output of every logger.
+type Redactor struct {
+	mu       sync.Mutex
+	keep     int
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+	secrets  []string
+	// refs counts how many times each secret was added.
+	refs map[string]int
+
+	// compiled *redactMatcher, rebuilt whenever a rule is added.
+	matcher atomic.Value
+}
+
+// NewRedactor returns a new, empty Redactor.
+func NewRedactor() *Redactor {
+	return &Redactor{
+		keys: map[string]bool{},
+		refs: map[string]int{},
+	}
+}
+
+// AddSecret adds literal strings to be redacted. The JSON-escaped form of
+// each secret is redacted as well.
+func (r *Redactor) AddSecret(secrets ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := false
+	for _, secret := range secrets {
+		if "" == secret {
+			continue
+		}
+		if 0 == r.refs[secret] {
+			r.secrets = append(r.secrets, secret)
+			changed = true
+		}
+		r.refs[secret]++
+	}
+	if changed {
+		r.compile()
+	}
+}
+
+// RemoveSecret removes literal strings added with `AddSecret`. A secret
+// added several times is redacted until it has been removed as many times,
+// so short-lived secrets, e.g. request credentials, can be added and removed
+// without removing a secret that is still in use.
+func (r *Redactor) RemoveSecret(secrets ...string) {
+	if nil == r {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	changed := false
+	for _, secret := range secrets {
+		if 0 == r.refs[secret] {
+			continue
+		}
+		r.refs[secret]--
+		if 0 < r.refs[secret] {
+			continue
+		}
+		delete(r.refs, secret)
+		for i, str := range r.secrets {
+			if str == secret {
+				r.secrets = append(r.secrets[:i], r.secrets[i+1:]...)
+				break
+			}
+		}
+		changed = true
+	}
+	if changed {
+		r.compile()
+	}
+}
+
+// AddPattern adds regular expressions to be redacted. Every match of a
+// pattern is redacted.
+func (r *Redactor) AddPattern(patterns ...string) error {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if nil != err {
+			return fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+		res = append(res, re)
+	}
+	r.AddRegexp(res...)
+	return nil
+}
+
+// AddRegexp adds compiled regular expressions to be redacted.
+func (r *Redactor) AddRegexp(res ...*regexp.Regexp) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.patterns = append(r.patterns, res...)
+	r.compile()
+}
+
+// AddKey adds field keys whose values are always masked, e.g. "password" or
+// "authorization". Keys are matched case-insensitively against the whole
+// field name and against the last segment of a dotted field name, so
+// "password" also matches "user.password".
+func (r *Redactor) AddKey(keys ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = true
+	}
+	r.compile()
+}
+
+// SetKeep enables partial masking. Instead of being replaced by `Redacted`,
+// the last n characters of each redacted value are kept and the rest are
+// replaced by '*'. Values no longer than n characters are masked entirely.
+// A value of 0 disables partial masking.
+func (r *Redactor) SetKeep(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n < 0 {
+		n = 0
+	}
+	r.keep = n
+	r.compile()
+}
+
+// Redact returns s with all secrets and pattern matches redacted.
+func (r *Redactor) Redact(s string) string {
+	m := r.load()
+	if nil == m {
+		return s
+	}
+	spans := m.find([]byte(s))
+	if 0 == len(spans) {
+		return s
+	}
+	return string(m.replace([]byte(s), spans))
+}
+
+// RedactBytes returns b with all secrets and pattern matches redacted. b is
+// returned unmodified if it contains nothing to redact.
+func (r *Redactor) RedactBytes(b []byte) []byte {
+	m := r.load()
+	if nil == m {
+		return b
+	}
+	spans := m.find(b)
+	if 0 == len(spans) {
+		return b
+	}
+	return m.replace(b, spans)
+}
+
+// MatchKey reports whether values of the field named key are always masked.
+func (r *Redactor) MatchKey(key string) bool {
+	m := r.load()
+	if nil == m || 0 == len(m.keys) {
+		return false
+	}
+	key = strings.ToLower(key)
+	if m.keys[key] {
+		return true
+	}
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		return m.keys[key[i+1:]]
+	}
+	return false
+}
+
+// Mask returns the masked form of an entire value, honoring `SetKeep`.
+func (r *Redactor) Mask(s string) string {
+	keep := 0
+	if m := r.load(); nil != m {
+		keep = m.keep
+	}
+	return mask(s, keep)
+}
+
+// active reports whether any rule has been added.
+func (r *Redactor) active() bool {
+	m := r.load()
+	return nil != m && (len(m.nodes) > 0 || len(m.patterns) > 0 || len(m.keys) > 0)
+}
+
+func (r *Redactor) load() *redactMatcher {
+	if nil == r {
+		return nil
+	}
+	m, _ := r.matcher.Load().(*redactMatcher)
+	return m
+}
+
+// compile rebuilds the matcher from the current rules. It must be called
+// with the lock held.
+func (r *Redactor) compile() {
+	literals := make([]string, 0, 2*len(r.secrets))
+	for _, secret := range r.secrets {
+		literals = append(literals, secret)
+		// JSON-encoded form of the secret, without the surrounding quotes.
+		if encoded, err := json.Marshal(secret); nil == err {
+			if escaped := string(encoded[1 : len(encoded)-1]); escaped != secret {
+				literals = append(literals, escaped)
+			}
+		}
+	}
+
+	m := &redactMatcher{
+		keep: r.keep,
+		keys: make(map[string]bool, len(r.keys)),
+	}
+	for key := range r.keys {
+		m.keys[key] = true
+	}
+	m.buildLiterals(literals)
+	m.patterns = append([]*regexp.Regexp(nil), r.patterns...)
+	r.matcher.Store(m)
+}
+
+// redactMatcher is the compiled, immutable form of a Redactor's rules.
+// Literal secrets are matched with an Aho-Corasick automaton. Regular
+// expressions are matched one by one, a single alternation would stop at the
+// first pattern matching at a position and miss longer overlapping matches.
+type redactMatcher struct {
+	keep     int
+	keys     map[string]bool
+	nodes    []redactNode
+	patterns []*regexp.Regexp
+	// dense copy of the root node's transitions, most input bytes do not
+	// start a secret and are resolved here.
+	root [256]int32
+}
+
+type redactNode struct {
+	next map[byte]int32
+	fail int32
+	// length of the longest literal that ends at this node, 0 if none.
+	out int
+}
+
+type redactSpan struct {
+	start, end int
+}
+
+func (m *redactMatcher) buildLiterals(literals []string) {
+	if 0 == len(literals) {
+		return
+	}
+	m.nodes = []redactNode{{next: map[byte]int32{}}}
+	for _, literal := range literals {
+		var state int32
+		for i := 0; i < len(literal); i++ {
+			next, ok := m.nodes[state].next[literal[i]]
+			if !ok {
+				m.nodes = append(m.nodes, redactNode{next: map[byte]int32{}})
+				next = int32(len(m.nodes) - 1)
+				m.nodes[state].next[literal[i]] = next
+			}
+			state = next
+		}
+		if len(literal) > m.nodes[state].out {
+			m.nodes[state].out = len(literal)
+		}
+	}
+
+	for c, child := range m.nodes[0].next {
+		m.root[c] = child
+	}
+
+	// Breadth-first construction of the failure links. A literal that ends
+	// at a node's failure target also ends at the node itself, so `out` is
+	// propagated along the links.
+	queue := make([]int32, 0, len(m.nodes))
+	for _, child := range m.nodes[0].next {
+		queue = append(queue, child)
+	}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for c, child := range m.nodes[state].next {
+			fail := m.nodes[state].fail
+			for {
+				if next, ok := m.nodes[fail].next[c]; ok {
+					m.nodes[child].fail = next
+					break
+				}
+				if 0 == fail {
+					break
+				}
+				fail = m.nodes[fail].fail
+			}
+			if out := m.nodes[m.nodes[child].fail].out; out > m.nodes[child].out {
+				m.nodes[child].out = out
+			}
+			queue = append(queue, child)
+		}
+	}
+}
+
+// find returns the sorted, merged spans of b that must be redacted.
+func (m *redactMatcher) find(b []byte) []redactSpan {
+	var spans []redactSpan
+
+	if len(m.nodes) > 0 {
+		var state int32
+		for i := 0; i < len(b); i++ {
+			if 0 == state {
+				if state = m.root[b[i]]; 0 == state {
+					continue
+				}
+			} else {
+				m.step(&state, b[i])
+			}
+			if out := m.nodes[state].out; out > 0 {
+				spans = append(spans, redactSpan{i + 1 - out, i + 1})
+			}
+		}
+	}
+
+	for _, pattern := range m.patterns {
+		for _, loc := range pattern.FindAllIndex(b, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, redactSpan{loc[0], loc[1]})
+			}
+		}
+	}
+
+	if len(spans) < 2 {
+		return spans
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start < spans[j].start
+	})
+	merged := spans[:1]
+	for _, span := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if span.start <= last.end {
+			if span.end > last.end {
+				last.end = span.end
+			}
+			continue
+		}
+		merged = append(merged, span)
+	}
+	return merged
+}
+
+// step advances the automaton by one input byte.
+func (m *redactMatcher) step(state *int32, c byte) {
+	for {
+		if next, ok := m.nodes[*state].next[c]; ok {
+			*state = next
+			return
+		}
+		if 0 == *state {
+			return
+		}
+		*state = m.nodes[*state].fail
+	}
+}
+
+func (m *redactMatcher) replace(b []byte, spans []redactSpan) []byte {
+	result := make([]byte, 0, len(b))
+	prev := 0
+	for _, span := range spans {
+		result = append(result, b[prev:span.start]...)
+		result = append(result, mask(string(b[span.start:span.end]), m.keep)...)
+		prev = span.end
+	}
+	return append(result, b[prev:]...)
+}
+
+// mask returns the replacement for a redacted value.
+func mask(s string, keep int) string {
+	if keep <= 0 {
+		return Redacted
+	}
+	n := utf8.RuneCountInString(s)
+	if n <= keep {
+		return strings.Repeat("*", n)
+	}
+	runes := []rune(s)
+	return strings.Repeat("*", n-keep) + string(runes[n-keep:])
+}
+
+// globalRedactor holds the secrets added with the package-level AddSecret,
+// they are redacted from the output of every logger.
+var globalRedactor = NewRedactor()
+
+// AddSecret adds strings to the sanitization list of every logger. Use
+// `Logger.AddSecret` to limit a secret to a single logger.
+func AddSecret(secrets ...string) {
+	globalRedactor.AddSecret(secrets...)
+}
+
+// AddSecret adds strings to the sanitization list of this logger.
+func (logger *Logger) AddSecret(secrets ...string) {
+	logger = logger.root()
+	logger.mu.Lock()
+	if nil == logger.Redactor {
+		logger.Redactor = NewRedactor()
+	}
+	redactor := logger.Redactor
+	logger.mu.Unlock()
+	redactor.AddSecret(secrets...)
+}
+
+// RemoveSecret removes strings added with the package-level `AddSecret`.
+func RemoveSecret(secrets ...string) {
+	globalRedactor.RemoveSecret(secrets...)
+}
+
+// RemoveSecret removes strings added with `Logger.AddSecret`.
+func (logger *Logger) RemoveSecret(secrets ...string) {
+	logger.root().redactor().RemoveSecret(secrets...)
+}
+
+// redactor returns the logger's Redactor, it is set lazily by `AddSecret`.
+func (logger *Logger) redactor() *Redactor {
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	return logger.Redactor
+}
+
+// redact returns the serialized entry with all secrets redacted.
+func (logger *Logger) redact(serialized []byte) []byte {
+	return logger.redactor().RedactBytes(globalRedactor.RedactBytes(serialized))
+}
@@ -0,0 +1,437 @@
+package log
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Struct tag values recognized by the structured redaction stage:
+//
+//	type User struct {
+//		Name     string
+//		Password string `log:"redact"` // logged masked
+//		Session  string `log:"-"`      // never logged
+//	}
+const (
+	// TagName is the struct tag key read by the redaction stage.
+	TagName = "log"
+	// TagRedact masks the field value.
+	TagRedact = "redact"
+	// TagOmit removes the field from the output.
+	TagOmit = "-"
+)
+
+// maxRedactDepth limits how deep nested values are walked.
+const maxRedactDepth = 32
+
+// visit identifies a map or pointer being walked. The type is part of the
+// key because a struct and its first field share an address.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// redactors applies the rules of several redactors at once.
+type redactors []*Redactor
+
+// redact redacts the entry's message, error and data before hooks and
+// formatters see them. Values are copied before they are modified, maps and
+// structs shared with other entries are never changed.
+func (entry *Entry) redact() {
+	rs := redactors{globalRedactor, entry.Logger.redactor()}
+	active := rs.active()
+	if active {
+		entry.Message = rs.redactString(entry.Message)
+		entry.Err = rs.redactError(entry.Err)
+	}
+
+	var data Fields
+	for k, v := range entry.Data {
+		redacted, changed := rs.field(k, v, active, 0, nil)
+		if !changed {
+			continue
+		}
+		if nil == data {
+			data = make(Fields, len(entry.Data))
+			for k, v := range entry.Data {
+				data[k] = v
+			}
+		}
+		data[k] = redacted
+	}
+	if nil != data {
+		entry.Data = data
+	}
+
+	var typed []Field
+	for i, f := range entry.TypedData {
+		redacted, changed := rs.typedField(f, active)
+		if !changed {
+			continue
+		}
+		if nil == typed {
+			typed = make([]Field, len(entry.TypedData))
+			copy(typed, entry.TypedData)
+		}
+		typed[i] = redacted
+	}
+	if nil != typed {
+		entry.TypedData = typed
+	}
+}
+
+// typedField redacts a typed field. Scalar fields are only subject to key
+// rules, strings, errors and arbitrary values are redacted like map fields.
+func (rs redactors) typedField(f Field, active bool) (Field, bool) {
+	if active {
+		if r := rs.matchKey(f.Key); nil != r {
+			return String(f.Key, r.Mask(f.text())), true
+		}
+	}
+	switch f.Type {
+	case StringType:
+		if active {
+			if redacted := rs.redactString(f.String); redacted != f.String {
+				return String(f.Key, redacted), true
+			}
+		}
+	case ErrorType:
+		if err, ok := f.Interface.(error); ok && nil != err && active {
+			if redacted, changed := rs.redactErrorChain(err); changed {
+				return NamedErr(f.Key, redacted), true
+			}
+		}
+	case AnyType:
+		if redacted, changed := rs.field(f.Key, f.Interface, active, 0, nil); changed {
+			return Any(f.Key, redacted), true
+		}
+	}
+	return f, false
+}
+
+func (rs redactors) active() bool {
+	for _, r := range rs {
+		if r.active() {
+			return true
+		}
+	}
+	return false
+}
+
+func (rs redactors) redactString(s string) string {
+	for _, r := range rs {
+		s = r.Redact(s)
+	}
+	return s
+}
+
+// matchKey returns the redactor with a key rule matching key, if any.
+func (rs redactors) matchKey(key string) *Redactor {
+	for _, r := range rs {
+		if r.MatchKey(key) {
+			return r
+		}
+	}
+	return nil
+}
+
+// field redacts a single named value at depth. active reports whether any
+// redaction rule is configured, if not only struct tags are honored.
+// visiting holds the maps and pointers being walked, see value.
+func (rs redactors) field(key string, v interface{}, active bool, depth int, visiting map[visit]bool) (interface{}, bool) {
+	if active {
+		if r := rs.matchKey(key); nil != r {
+			return r.Mask(fmt.Sprintf("%v", v)), true
+		}
+	}
+	if nil == v {
+		return v, false
+	}
+	if !active && !typeNeedsWalk(reflect.TypeOf(v)) {
+		return v, false
+	}
+	return rs.value(reflect.ValueOf(v), active, depth, visiting)
+}
+
+// value walks v and returns its redacted form. Maps and structs that contain
+// redacted values are returned as map[string]interface{}, slices and arrays as
+// []interface{}. If nothing was redacted v is returned unchanged.
+//
+// Values nested deeper than maxRedactDepth and references back to a map or
+// pointer that is being walked are replaced by nil, so a copy of a cyclic
+// value never points back to the unredacted original.
+func (rs redactors) value(v reflect.Value, active bool, depth int, visiting map[visit]bool) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+	if depth > maxRedactDepth {
+		return nil, true
+	}
+	if (reflect.Ptr == v.Kind() || reflect.Map == v.Kind()) && !v.IsNil() {
+		p := visit{ptr: v.Pointer(), typ: v.Type()}
+		if visiting[p] {
+			return nil, true
+		}
+		if nil == visiting {
+			visiting = map[visit]bool{}
+		}
+		visiting[p] = true
+		defer delete(visiting, p)
+	}
+	if v.CanInterface() {
+		if err, ok := v.Interface().(error); ok && active {
+			if isNilValue(v) {
+				return err, false
+			}
+			redacted := rs.redactError(err)
+			return redacted, redacted != err
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if !active {
+			return v.Interface(), false
+		}
+		s := v.String()
+		if redacted := rs.redactString(s); redacted != s {
+			return redacted, true
+		}
+
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			break
+		}
+		if elem, changed := rs.value(v.Elem(), active, depth+1, visiting); changed {
+			return elem, true
+		}
+
+	case reflect.Map:
+		if v.IsNil() || (!active && !typeNeedsWalk(v.Type().Elem())) {
+			break
+		}
+		var result map[string]interface{}
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprintf("%v", iter.Key().Interface())
+			elem, changed := rs.field(key, iter.Value().Interface(), active, depth+1, visiting)
+			if changed && nil == result {
+				result = make(map[string]interface{}, v.Len())
+				for _, k := range v.MapKeys() {
+					result[fmt.Sprintf("%v", k.Interface())] = v.MapIndex(k).Interface()
+				}
+			}
+			if nil != result {
+				result[key] = elem
+			}
+		}
+		if nil != result {
+			return result, true
+		}
+
+	case reflect.Slice, reflect.Array:
+		if (reflect.Slice == v.Kind() && v.IsNil()) ||
+			reflect.Uint8 == v.Type().Elem().Kind() ||
+			(!active && !typeNeedsWalk(v.Type().Elem())) {
+			break
+		}
+		var result []interface{}
+		for i := 0; i < v.Len(); i++ {
+			elem, changed := rs.value(v.Index(i), active, depth+1, visiting)
+			if changed && nil == result {
+				result = make([]interface{}, v.Len())
+				for j := 0; j < i; j++ {
+					result[j] = v.Index(j).Interface()
+				}
+			}
+			if nil != result {
+				result[i] = elem
+			}
+		}
+		if nil != result {
+			return result, true
+		}
+
+	case reflect.Struct:
+		if result, changed := rs.structValue(v, active, depth, visiting); changed {
+			return result, true
+		}
+	}
+
+	if v.CanInterface() {
+		return v.Interface(), false
+	}
+	return nil, false
+}
+
+// structValue redacts the exported fields of a struct. Field names are taken
+// from the `json` tag when present so the output keeps its usual shape.
+func (rs redactors) structValue(v reflect.Value, active bool, depth int, visiting map[visit]bool) (map[string]interface{}, bool) {
+	t := v.Type()
+	if !active && !typeNeedsWalk(t) {
+		return nil, false
+	}
+
+	changed := false
+	result := make(map[string]interface{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if "" != sf.PkgPath {
+			continue
+		}
+		name, omitEmpty, skip := jsonFieldName(sf)
+		if skip {
+			continue
+		}
+		fv := v.Field(i)
+
+		switch sf.Tag.Get(TagName) {
+		case TagOmit:
+			changed = true
+			continue
+		case TagRedact:
+			changed = true
+			result[name] = rs.mask(fmt.Sprintf("%v", fv.Interface()))
+			continue
+		}
+
+		if omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		elem, fieldChanged := rs.field(name, fv.Interface(), active, depth+1, visiting)
+		changed = changed || fieldChanged
+		result[name] = elem
+	}
+	return result, changed
+}
+
+// mask masks a value tagged for redaction using the most specific redactor.
+func (rs redactors) mask(s string) string {
+	for i := len(rs) - 1; i >= 0; i-- {
+		if nil != rs[i] {
+			return rs[i].Mask(s)
+		}
+	}
+	return Redacted
+}
+
+// redactError returns err with secrets removed from its message. The
+// wrapped errors are redacted as well so walking the chain does not reveal
+// them. err is returned unchanged if it does not contain any secrets.
+func (rs redactors) redactError(err error) error {
+	redacted, _ := rs.redactErrorChain(err)
+	return redacted
+}
+
+func (rs redactors) redactErrorChain(err error) (error, bool) {
+	if nil == err {
+		return nil, false
+	}
+	msg := err.Error()
+	redacted := rs.redactString(msg)
+	cause, causeChanged := rs.redactErrorChain(errors.Unwrap(err))
+	if redacted == msg && !causeChanged {
+		return err, false
+	}
+	return &redactedError{msg: redacted, cause: cause}, true
+}
+
+// redactedError replaces an error whose message contained secrets.
+type redactedError struct {
+	msg   string
+	cause error
+}
+
+// Error implements error.
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+// Unwrap returns the redacted cause.
+func (e *redactedError) Unwrap() error {
+	return e.cause
+}
+
+func jsonFieldName(sf reflect.StructField) (name string, omitEmpty, skip bool) {
+	name = sf.Name
+	tag := sf.Tag.Get("json")
+	if "-" == tag {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	if "" != parts[0] {
+		name = parts[0]
+	}
+	for _, opt := range parts[1:] {
+		if "omitempty" == opt {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return 0 == v.Len()
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 0 == v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return 0 == v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return 0 == v.Float()
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// walkCache caches typeNeedsWalk results by reflect.Type.
+var walkCache sync.Map
+
+// typeNeedsWalk reports whether values of type t may contain fields tagged
+// for redaction. Interface types always need to be walked because their
+// dynamic type is only known at runtime.
+func typeNeedsWalk(t reflect.Type) bool {
+	if cached, ok := walkCache.Load(t); ok {
+		return cached.(bool)
+	}
+	// Store a conservative provisional result to terminate recursive types.
+	walkCache.Store(t, true)
+	result := computeNeedsWalk(t)
+	walkCache.Store(t, result)
+	return result
+}
+
+func computeNeedsWalk(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return typeNeedsWalk(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if "" != sf.PkgPath {
+				continue
+			}
+			if "" != sf.Tag.Get(TagName) || typeNeedsWalk(sf.Type) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,184 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+type redactUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password" log:"redact"`
+	Session  string `log:"-"`
+	Note     string `json:"note,omitempty"`
+	Nested   *redactUser
+	private  string
+}
+
+type plainStruct struct {
+	Name string
+	Note string
+}
+
+type RecordHook struct {
+	Entries []Entry
+}
+
+func (hook *RecordHook) Fire(entry *Entry) error {
+	hook.Entries = append(hook.Entries, *entry)
+	return nil
+}
+
+func (hook *RecordHook) Levels() []stdLogger.Level {
+	return AllLevels
+}
+
+func TestRedactStructTags(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.WithField("user", redactUser{
+			Name:     "bob",
+			Password: "hunter2",
+			Session:  "abc",
+			Nested:   &redactUser{Name: "alice", Password: "pw"},
+			private:  "x",
+		}).Info("login")
+	}, func(data logData) {
+		user := data.Data["user"].(map[string]interface{})
+		assert.Equal(t, "bob", user["name"])
+		assert.Equal(t, Redacted, user["password"])
+		assert.NotContains(t, user, "Session")
+		assert.NotContains(t, user, "note")
+		assert.NotContains(t, user, "private")
+		nested := user["Nested"].(map[string]interface{})
+		assert.Equal(t, "alice", nested["name"])
+		assert.Equal(t, Redacted, nested["password"])
+	})
+}
+
+func TestRedactNestedSecrets(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddSecret(`sec"ret`)
+		log.Redactor.AddKey("token")
+		log.WithFields(Fields{
+			"list":   []string{"a", `the sec"ret`},
+			"map":    map[string]interface{}{"token": "abc", "ok": "fine"},
+			"struct": plainStruct{Name: "bob", Note: `sec"ret note`},
+			"plain":  plainStruct{Name: "bob"},
+		}).Info("nested")
+	}, func(data logData) {
+		assert.Equal(t, []interface{}{"a", "the " + Redacted}, data.Data["list"])
+		assert.Equal(t, map[string]interface{}{"token": Redacted, "ok": "fine"}, data.Data["map"])
+		assert.Equal(t, map[string]interface{}{"Name": "bob", "Note": Redacted + " note"}, data.Data["struct"])
+		assert.Equal(t, map[string]interface{}{"Name": "bob", "Note": ""}, data.Data["plain"])
+	})
+}
+
+func TestRedactMessageAndError(t *testing.T) {
+	LogAndAssertJSON(t, func(log *Logger) {
+		log.AddSecret("hunter2")
+		err := fmt.Errorf("login failed: %w", fmt.Errorf("bad password hunter2"))
+		log.WithError(err).Error("password hunter2 rejected")
+	}, func(data logData) {
+		assert.Equal(t, "password [REDACTED] rejected", data.Message)
+		assert.Equal(t, "login failed: bad password [REDACTED]", data.Err.(error).Error())
+	})
+}
+
+func TestRedactBeforeHooks(t *testing.T) {
+	hook := new(RecordHook)
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddSecret("hunter2")
+	logger.Redactor.AddKey("password")
+	logger.AddHook(hook)
+
+	err := fmt.Errorf("bad password hunter2")
+	logger.WithError(err).WithFields(Fields{
+		"password": "hunter2",
+		"user":     redactUser{Name: "bob", Password: "pw"},
+	}).Info("the password is hunter2")
+
+	if assert.Len(t, hook.Entries, 1) {
+		entry := hook.Entries[0]
+		assert.Equal(t, "the password is [REDACTED]", entry.Message)
+		assert.Equal(t, "bad password [REDACTED]", entry.Err.Error())
+		assert.Equal(t, Redacted, entry.Data["password"])
+		assert.Equal(t, Redacted, entry.Data["user"].(map[string]interface{})["password"])
+	}
+}
+
+func TestRedactDoesNotModifyOriginal(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.AddSecret("hunter2")
+
+	list := []string{"hunter2"}
+	user := &redactUser{Password: "pw"}
+	entry := logger.WithFields(Fields{"list": list, "user": user})
+	entry.Info("test")
+
+	assert.Equal(t, "hunter2", list[0])
+	assert.Equal(t, "pw", user.Password)
+	assert.Equal(t, list, entry.Data["list"])
+}
+
+func TestRedactWithoutRules(t *testing.T) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	hook := new(RecordHook)
+	logger.AddHook(hook)
+
+	data := map[string]int{"a": 1}
+	logger.WithFields(Fields{"plain": plainStruct{Name: "bob"}, "map": data}).Info("test")
+
+	if assert.Len(t, hook.Entries, 1) {
+		assert.Equal(t, plainStruct{Name: "bob"}, hook.Entries[0].Data["plain"])
+		assert.Equal(t, data, hook.Entries[0].Data["map"])
+	}
+}
+
+type cyclicNode struct {
+	Name string
+	Next *cyclicNode
+	Prev *cyclicNode
+}
+
+func TestRedactCycles(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &TextFormatter{DisableTTY: true}
+	logger.AddSecret("hunter2")
+
+	node := &cyclicNode{Name: "a"}
+	node.Next, node.Prev = node, node
+	logger.WithField("node", node).Info("x")
+	assert.Contains(t, buffer.String(), `msg="x"`)
+
+	// A redacted copy doesn't point back to the original.
+	node.Name = "hunter2"
+	entry := NewEntry(logger).WithField("node", node)
+	entry.redact()
+	assert.Equal(t, map[string]interface{}{"Name": Redacted, "Next": nil, "Prev": nil}, entry.Data["node"])
+
+	m := map[string]interface{}{"password": "hunter2"}
+	m["self"] = m
+	entry = NewEntry(logger).WithField("map", m)
+	entry.redact()
+	redacted, ok := entry.Data["map"].(map[string]interface{})
+	if assert.True(t, ok) {
+		assert.Equal(t, Redacted, redacted["password"])
+		assert.Nil(t, redacted["self"])
+	}
+	assert.Equal(t, "hunter2", m["password"])
+
+	// Without redaction rules only types with struct tags are walked.
+	user := &redactUser{Name: "bob", Password: "pw"}
+	user.Nested = user
+	entry = NewEntry(New()).WithField("user", user)
+	entry.redact()
+	assert.Equal(t, map[string]interface{}{"name": "bob", "password": Redacted, "Nested": nil}, entry.Data["user"])
+}
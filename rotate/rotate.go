@@ -0,0 +1,350 @@
+/*
+Package rotate implements a rotating file writer that can be used as the
+output of a logger.
+
+	f, err := rotate.New("/var/log/app.log", rotate.Options{
+		MaxSize:    100 << 20,
+		Interval:   24 * time.Hour,
+		MaxBackups: 7,
+		Compress:   true,
+	})
+	if nil != err {
+		panic(err)
+	}
+	defer f.Close()
+	logger.Out = f
+
+Rotated files are kept next to the active file and are named after it with
+the rotation time inserted before the extension, e.g.
+"app-2006-01-02T15-04-05.000.log". Compressed backups get an additional
+".gz" extension.
+*/
+package rotate
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the layout of the timestamp in backup file names.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Options configures a rotating File. The zero value never rotates
+// automatically.
+type Options struct {
+	// MaxSize is the size in bytes at which the file is rotated. 0 disables
+	// size based rotation.
+	MaxSize int64
+
+	// Interval rotates the file at every multiple of the interval, e.g.
+	// every hour on the hour. Boundaries are aligned to UTC, so a 24 hour
+	// interval rotates at midnight UTC. 0 disables time based rotation.
+	Interval time.Duration
+
+	// MaxBackups is the maximum number of rotated files to keep. 0 keeps all
+	// backups.
+	MaxBackups int
+
+	// MaxAge is the maximum age of rotated files, based on the timestamp in
+	// their name. 0 keeps backups regardless of age.
+	MaxAge time.Duration
+
+	// Compress gzips rotated files.
+	Compress bool
+
+	// Mode is the permission of newly created files, defaults to 0644.
+	Mode os.FileMode
+}
+
+// File is an io.WriteCloser that writes to a file and rotates it based on
+// size and time. The file is opened in append mode, so it can be shared with
+// other writers as assumed by `log.Logger.SetNoLock`. It is safe for
+// concurrent use.
+type File struct {
+	path string
+	opts Options
+	now  func() time.Time
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	next time.Time
+
+	// serializes cleanup and compression of backups.
+	millMu sync.Mutex
+	millWG sync.WaitGroup
+}
+
+// New opens or creates the file at path, creating missing directories.
+func New(path string, opts Options) (*File, error) {
+	if opts.MaxSize < 0 || opts.Interval < 0 || opts.MaxBackups < 0 || opts.MaxAge < 0 {
+		return nil, fmt.Errorf("rotate: negative option value")
+	}
+	if 0 == opts.Mode {
+		opts.Mode = 0644
+	}
+	f := &File{
+		path: path,
+		opts: opts,
+		now:  time.Now,
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.open(); nil != err {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Path returns the path of the active file.
+func (f *File) Path() string {
+	return f.path
+}
+
+// Write implements io.Writer. The file is rotated before the write if it
+// would exceed MaxSize or the rotation interval has elapsed.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if nil == f.file {
+		if err := f.open(); nil != err {
+			return 0, err
+		}
+	}
+	if f.due(int64(len(p))) {
+		if err := f.rotate(); nil != err {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate closes the active file, renames it to a backup and opens a new
+// file.
+func (f *File) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.rotate()
+}
+
+// Reopen closes and reopens the active file without renaming it. Use it
+// after an external tool such as logrotate has moved the file, see
+// `ReopenOnSignal`.
+func (f *File) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.close(); nil != err {
+		return err
+	}
+	return f.open()
+}
+
+// Close closes the active file and waits for pending backup compression and
+// cleanup. Writing after Close reopens the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	err := f.close()
+	f.mu.Unlock()
+	f.millWG.Wait()
+	return err
+}
+
+// due reports whether the file must be rotated before writing n bytes. It
+// must be called with the lock held.
+func (f *File) due(n int64) bool {
+	if f.opts.MaxSize > 0 && f.size > 0 && f.size+n > f.opts.MaxSize {
+		return true
+	}
+	return f.opts.Interval > 0 && !f.now().Before(f.next)
+}
+
+// open opens the active file. It must be called with the lock held.
+func (f *File) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0755); nil != err {
+		return fmt.Errorf("rotate: %v", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, f.opts.Mode)
+	if nil != err {
+		return fmt.Errorf("rotate: %v", err)
+	}
+	info, err := file.Stat()
+	if nil != err {
+		file.Close()
+		return fmt.Errorf("rotate: %v", err)
+	}
+	f.file = file
+	f.size = info.Size()
+	if f.opts.Interval > 0 {
+		f.next = f.now().Truncate(f.opts.Interval).Add(f.opts.Interval)
+	}
+	return nil
+}
+
+// close closes the active file. It must be called with the lock held.
+func (f *File) close() error {
+	if nil == f.file {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// rotate must be called with the lock held.
+func (f *File) rotate() error {
+	if err := f.close(); nil != err {
+		return err
+	}
+	if _, err := os.Stat(f.path); nil == err {
+		if err := os.Rename(f.path, f.backupName(f.now())); nil != err {
+			return fmt.Errorf("rotate: %v", err)
+		}
+	}
+	if err := f.open(); nil != err {
+		return err
+	}
+	if f.opts.MaxBackups > 0 || f.opts.MaxAge > 0 || f.opts.Compress {
+		f.millWG.Add(1)
+		go func() {
+			defer f.millWG.Done()
+			f.mill()
+		}()
+	}
+	return nil
+}
+
+// prefixAndExt returns the parts of the backup file names around the
+// timestamp.
+func (f *File) prefixAndExt() (string, string) {
+	name := filepath.Base(f.path)
+	ext := filepath.Ext(name)
+	return strings.TrimSuffix(name, ext) + "-", ext
+}
+
+// backupName returns an unused backup file name for a rotation at t.
+func (f *File) backupName(t time.Time) string {
+	prefix, ext := f.prefixAndExt()
+	dir := filepath.Dir(f.path)
+	for {
+		name := filepath.Join(dir, prefix+t.Format(backupTimeFormat)+ext)
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// backup is a rotated file.
+type backup struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (f *File) backups() ([]backup, error) {
+	entries, err := ioutil.ReadDir(filepath.Dir(f.path))
+	if nil != err {
+		return nil, err
+	}
+	prefix, ext := f.prefixAndExt()
+	result := []backup{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimPrefix(strings.TrimSuffix(name, ".gz"), prefix)
+		if !strings.HasSuffix(stamp, ext) {
+			continue
+		}
+		t, err := time.ParseInLocation(backupTimeFormat, strings.TrimSuffix(stamp, ext), time.Local)
+		if nil != err {
+			continue
+		}
+		result = append(result, backup{path: filepath.Join(filepath.Dir(f.path), name), time: t})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].time.After(result[j].time)
+	})
+	return result, nil
+}
+
+// mill removes expired backups and compresses the remaining ones.
+func (f *File) mill() {
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+
+	backups, err := f.backups()
+	if nil != err {
+		fmt.Fprintf(os.Stderr, "rotate: failed to list backups, %v\n", err)
+		return
+	}
+	cutoff := f.now().Add(-f.opts.MaxAge)
+	for i, b := range backups {
+		if (f.opts.MaxBackups > 0 && i >= f.opts.MaxBackups) ||
+			(f.opts.MaxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); nil != err && !os.IsNotExist(err) {
+				fmt.Fprintf(os.Stderr, "rotate: failed to remove backup, %v\n", err)
+			}
+			continue
+		}
+		if f.opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compress(b.path, f.opts.Mode); nil != err {
+				fmt.Fprintf(os.Stderr, "rotate: failed to compress backup, %v\n", err)
+			}
+		}
+	}
+}
+
+// compress gzips the file at path and removes the original.
+func compress(path string, mode os.FileMode) (err error) {
+	src, err := os.Open(path)
+	if nil != err {
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if nil != err {
+		return err
+	}
+	defer func() {
+		if nil != err {
+			dst.Close()
+			os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); nil != err {
+		return err
+	}
+	if err = gz.Close(); nil != err {
+		return err
+	}
+	if err = dst.Close(); nil != err {
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); nil != err {
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return nil == err
+}
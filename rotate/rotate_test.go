@@ -0,0 +1,260 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rotate")
+	if nil != err {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// clock is a manually advanced time source.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newFile(t *testing.T, opts Options) (*File, *clock) {
+	c := &clock{t: time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)}
+	f, err := New(filepath.Join(tempDir(t), "app.log"), opts)
+	if nil != err {
+		t.Fatal(err)
+	}
+	f.now = c.now
+	if opts.Interval > 0 {
+		f.next = c.now().Truncate(opts.Interval).Add(opts.Interval)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f, c
+}
+
+func files(t *testing.T, f *File) []string {
+	entries, err := ioutil.ReadDir(filepath.Dir(f.Path()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func read(t *testing.T, path string) string {
+	b, err := ioutil.ReadFile(path)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func write(t *testing.T, f *File, s string) {
+	if _, err := f.Write([]byte(s)); nil != err {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	f, c := newFile(t, Options{MaxSize: 11})
+
+	write(t, f, "12345\n")
+	write(t, f, "1234\n")
+	c.add(time.Second)
+	write(t, f, "abcdef\n")
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{"app-2024-01-02T03-04-06.000.log", "app.log"}, files(t, f))
+	assert.Equal(t, "abcdef\n", read(t, f.Path()))
+	assert.Equal(t, "12345\n1234\n", read(t, filepath.Join(filepath.Dir(f.Path()), "app-2024-01-02T03-04-06.000.log")))
+}
+
+func TestRotateOversizedWrite(t *testing.T) {
+	f, _ := newFile(t, Options{MaxSize: 4})
+
+	write(t, f, "0123456789\n")
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{"app.log"}, files(t, f))
+}
+
+func TestRotateByInterval(t *testing.T) {
+	f, c := newFile(t, Options{Interval: time.Hour})
+
+	write(t, f, "first\n")
+	c.add(30 * time.Minute)
+	write(t, f, "second\n")
+	c.add(30 * time.Minute)
+	write(t, f, "third\n")
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{"app-2024-01-02T04-04-05.000.log", "app.log"}, files(t, f))
+	assert.Equal(t, "third\n", read(t, f.Path()))
+}
+
+func TestRotateMaxBackups(t *testing.T) {
+	f, c := newFile(t, Options{MaxBackups: 2})
+
+	for i := 0; i < 4; i++ {
+		write(t, f, "line\n")
+		c.add(time.Second)
+		assert.NoError(t, f.Rotate())
+	}
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{
+		"app-2024-01-02T03-04-08.000.log",
+		"app-2024-01-02T03-04-09.000.log",
+		"app.log",
+	}, files(t, f))
+}
+
+func TestRotateMaxAge(t *testing.T) {
+	f, c := newFile(t, Options{MaxAge: time.Hour})
+
+	write(t, f, "old\n")
+	assert.NoError(t, f.Rotate())
+	c.add(2 * time.Hour)
+	write(t, f, "new\n")
+	assert.NoError(t, f.Rotate())
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{"app-2024-01-02T05-04-05.000.log", "app.log"}, files(t, f))
+}
+
+func TestRotateCompress(t *testing.T) {
+	f, _ := newFile(t, Options{Compress: true})
+
+	write(t, f, "compressed\n")
+	assert.NoError(t, f.Rotate())
+	assert.NoError(t, f.Close())
+
+	names := files(t, f)
+	if assert.Equal(t, []string{"app-2024-01-02T03-04-05.000.log.gz", "app.log"}, names) {
+		gz, err := os.Open(filepath.Join(filepath.Dir(f.Path()), names[0]))
+		if nil != err {
+			t.Fatal(err)
+		}
+		defer gz.Close()
+		r, err := gzip.NewReader(gz)
+		if nil != err {
+			t.Fatal(err)
+		}
+		b, err := ioutil.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "compressed\n", string(b))
+	}
+}
+
+func TestRotateBackupNameCollision(t *testing.T) {
+	f, _ := newFile(t, Options{})
+
+	write(t, f, "one\n")
+	assert.NoError(t, f.Rotate())
+	write(t, f, "two\n")
+	assert.NoError(t, f.Rotate())
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, []string{
+		"app-2024-01-02T03-04-05.000.log",
+		"app-2024-01-02T03-04-05.001.log",
+		"app.log",
+	}, files(t, f))
+}
+
+func TestRotateReopen(t *testing.T) {
+	f, _ := newFile(t, Options{})
+	write(t, f, "before\n")
+
+	// Simulate logrotate moving the file away.
+	moved := f.Path() + ".1"
+	assert.NoError(t, os.Rename(f.Path(), moved))
+	write(t, f, "still old\n")
+	assert.NoError(t, f.Reopen())
+	write(t, f, "after\n")
+
+	assert.NoError(t, f.Close())
+	assert.Equal(t, "before\nstill old\n", read(t, moved))
+	assert.Equal(t, "after\n", read(t, f.Path()))
+}
+
+func TestRotateAppendsToExistingFile(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "logs", "app.log")
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	assert.NoError(t, ioutil.WriteFile(path, []byte("existing\n"), 0644))
+
+	f, err := New(path, Options{MaxSize: 13})
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	write(t, f, "new\n")
+	assert.Equal(t, "existing\nnew\n", read(t, path))
+	write(t, f, "rotated\n")
+	assert.Equal(t, "rotated\n", read(t, path))
+}
+
+func TestRotateLoggerNoLock(t *testing.T) {
+	f, _ := newFile(t, Options{MaxSize: 512})
+
+	logger := log.New()
+	logger.Out = f
+	logger.Formatter = &log.JSONFormatter{DisableTTY: true}
+	logger.SetNoLock()
+
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		go func() {
+			for j := 0; j < 50; j++ {
+				logger.Info("concurrent")
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		<-done
+	}
+	assert.NoError(t, f.Close())
+
+	count := 0
+	for _, name := range files(t, f) {
+		for _, line := range strings.Split(strings.TrimSpace(read(t, filepath.Join(filepath.Dir(f.Path()), name))), "\n") {
+			assert.Contains(t, line, `"msg":"concurrent"`)
+			count++
+		}
+	}
+	assert.Equal(t, 200, count)
+}
+
+func TestNewInvalidOptions(t *testing.T) {
+	_, err := New(filepath.Join(tempDir(t), "app.log"), Options{MaxSize: -1})
+	assert.Error(t, err)
+}
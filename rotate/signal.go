@@ -0,0 +1,40 @@
+package rotate
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+// ReopenOnSignal reopens the file whenever one of the signals is received,
+// by default SIGHUP where it is supported. This matches the behavior
+// expected by logrotate's default (non copytruncate) mode. The returned
+// function stops listening for the signals.
+func (f *File) ReopenOnSignal(sigs ...os.Signal) (stop func()) {
+	if 0 == len(sigs) {
+		sigs = reopenSignals
+	}
+	if 0 == len(sigs) {
+		return func() {}
+	}
+
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sigs...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				if err := f.Reopen(); nil != err {
+					fmt.Fprintf(os.Stderr, "rotate: failed to reopen %s, %v\n", f.path, err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
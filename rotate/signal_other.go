@@ -0,0 +1,8 @@
+//go:build windows || plan9 || js || nacl
+// +build windows plan9 js nacl
+
+package rotate
+
+import "os"
+
+var reopenSignals []os.Signal
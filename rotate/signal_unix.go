@@ -0,0 +1,11 @@
+//go:build !windows && !plan9 && !js && !nacl
+// +build !windows,!plan9,!js,!nacl
+
+package rotate
+
+import (
+	"os"
+	"syscall"
+)
+
+var reopenSignals = []os.Signal{syscall.SIGHUP}
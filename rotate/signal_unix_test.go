@@ -0,0 +1,30 @@
+//go:build !windows && !plan9 && !js && !nacl
+// +build !windows,!plan9,!js,!nacl
+
+package rotate
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateReopenOnSignal(t *testing.T) {
+	f, _ := newFile(t, Options{})
+	stop := f.ReopenOnSignal(syscall.SIGUSR1)
+	defer stop()
+
+	moved := f.Path() + ".1"
+	assert.NoError(t, os.Rename(f.Path(), moved))
+	assert.NoError(t, syscall.Kill(os.Getpid(), syscall.SIGUSR1))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !exists(f.Path()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	write(t, f, "after\n")
+	assert.Equal(t, "after\n", read(t, f.Path()))
+}
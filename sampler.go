@@ -0,0 +1,150 @@
+package log
+
+import (
+	"sync/atomic"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// SuppressedField is the data field that reports how many entries with the
+// same level and message were dropped by the sampler since the last one that
+// was logged.
+const SuppressedField = "suppressed"
+
+// DefaultSamplingInterval is used when a SamplingRule does not set an
+// interval.
+const DefaultSamplingInterval = time.Second
+
+// sampleBuckets is the number of counters per level. Messages are hashed
+// into the buckets, messages that collide share a counter.
+const sampleBuckets = 4096
+
+// SamplingRule limits the number of entries logged at a level. Within each
+// interval the first `First` entries with a given message are logged, after
+// that only every `Thereafter`th entry is. A `Thereafter` of 0 drops all
+// entries after the first `First`.
+type SamplingRule struct {
+	Interval   time.Duration
+	First      uint64
+	Thereafter uint64
+}
+
+// Sampler drops repeated entries according to per-level rules, see
+// `Logger.SetSampler`. Levels without a rule are never sampled. Panic and
+// Fatal entries are never sampled.
+type Sampler struct {
+	levels map[stdLogger.Level]*levelSampler
+}
+
+// NewSampler returns a Sampler with a rule for each level in rules.
+//
+//	logger.SetSampler(log.NewSampler(map[stdLogger.Level]log.SamplingRule{
+//		log.WarnLevel: {Interval: time.Second, First: 10, Thereafter: 100},
+//		log.InfoLevel: {Interval: time.Second, First: 100, Thereafter: 1000},
+//	}))
+func NewSampler(rules map[stdLogger.Level]SamplingRule) *Sampler {
+	s := &Sampler{
+		levels: make(map[stdLogger.Level]*levelSampler, len(rules)),
+	}
+	for level, rule := range rules {
+		if level <= PanicLevel {
+			continue
+		}
+		if rule.Interval <= 0 {
+			rule.Interval = DefaultSamplingInterval
+		}
+		s.levels[level] = &levelSampler{
+			rule:     rule,
+			counters: make([]sampleCounter, sampleBuckets),
+		}
+	}
+	return s
+}
+
+// check reports whether an entry should be logged and, if so, how many
+// entries sharing its counter were suppressed since the last one logged.
+func (s *Sampler) check(level stdLogger.Level, msg string, now time.Time) (bool, uint64) {
+	if nil == s {
+		return true, 0
+	}
+	ls, ok := s.levels[level]
+	if !ok {
+		return true, 0
+	}
+	counter := &ls.counters[fnv32a(msg)%sampleBuckets]
+
+	n := counter.inc(now, ls.rule.Interval)
+	if n <= ls.rule.First ||
+		(ls.rule.Thereafter > 0 && 0 == (n-ls.rule.First)%ls.rule.Thereafter) {
+		return true, atomic.SwapUint64(&counter.suppressed, 0)
+	}
+	atomic.AddUint64(&counter.suppressed, 1)
+	return false, 0
+}
+
+// fnv32a returns the 32-bit FNV-1a hash of s without allocating.
+func fnv32a(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	h := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= prime32
+	}
+	return h
+}
+
+type levelSampler struct {
+	rule     SamplingRule
+	counters []sampleCounter
+}
+
+// sampleCounter counts the entries in the current interval. All fields are
+// accessed atomically.
+type sampleCounter struct {
+	resetAt    int64
+	count      uint64
+	suppressed uint64
+}
+
+// inc increments the counter, resetting it first if the interval has
+// elapsed, and returns the new count.
+func (c *sampleCounter) inc(now time.Time, interval time.Duration) uint64 {
+	tn := now.UnixNano()
+	resetAt := atomic.LoadInt64(&c.resetAt)
+	if resetAt > tn {
+		return atomic.AddUint64(&c.count, 1)
+	}
+	atomic.StoreUint64(&c.count, 1)
+	if !atomic.CompareAndSwapInt64(&c.resetAt, resetAt, tn+int64(interval)) {
+		// Another goroutine reset the counter first.
+		return atomic.AddUint64(&c.count, 1)
+	}
+	return 1
+}
+
+// SetSampler sets the sampler consulted before an entry is built. A nil
+// sampler disables sampling.
+func (logger *Logger) SetSampler(sampler *Sampler) {
+	logger = logger.root()
+	logger.sampler.Store(sampler)
+}
+
+func (logger *Logger) getSampler() *Sampler {
+	s, _ := logger.sampler.Load().(*Sampler)
+	return s
+}
+
+// withSuppressed returns a copy of the fields with the number of suppressed
+// entries added as `SuppressedField`.
+func (fields Fields) withSuppressed(suppressed uint64) Fields {
+	data := make(Fields, len(fields)+1)
+	for k, v := range fields {
+		data[k] = v
+	}
+	data[SuppressedField] = suppressed
+	return data
+}
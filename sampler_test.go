@@ -0,0 +1,133 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	stdLogger "github.com/bdlm/std/v2/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSamplerCheck(t *testing.T) {
+	s := NewSampler(map[stdLogger.Level]SamplingRule{
+		WarnLevel: {Interval: time.Second, First: 2, Thereafter: 3},
+	})
+	now := time.Unix(100, 0)
+
+	logged := []int{}
+	for i := 1; i <= 10; i++ {
+		if ok, _ := s.check(WarnLevel, "hot", now); ok {
+			logged = append(logged, i)
+		}
+	}
+	assert.Equal(t, []int{1, 2, 5, 8}, logged)
+
+	// Other messages and levels have their own counters.
+	ok, _ := s.check(WarnLevel, "cold", now)
+	assert.True(t, ok)
+	for i := 0; i < 10; i++ {
+		ok, _ := s.check(InfoLevel, "hot", now)
+		assert.True(t, ok)
+	}
+
+	// The counter is reset after the interval and the suppressed count is
+	// reported once.
+	ok, suppressed := s.check(WarnLevel, "hot", now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(2), suppressed)
+	ok, suppressed = s.check(WarnLevel, "hot", now.Add(time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, uint64(0), suppressed)
+}
+
+func TestSamplerThereafterZero(t *testing.T) {
+	s := NewSampler(map[stdLogger.Level]SamplingRule{
+		ErrorLevel: {First: 1},
+	})
+	now := time.Unix(100, 0)
+
+	ok, _ := s.check(ErrorLevel, "msg", now)
+	assert.True(t, ok)
+	for i := 0; i < 5; i++ {
+		ok, _ = s.check(ErrorLevel, "msg", now)
+		assert.False(t, ok)
+	}
+}
+
+func TestSamplerIgnoresPanicAndFatal(t *testing.T) {
+	s := NewSampler(map[stdLogger.Level]SamplingRule{
+		PanicLevel: {First: 1},
+		FatalLevel: {First: 1},
+	})
+	for i := 0; i < 3; i++ {
+		ok, _ := s.check(PanicLevel, "msg", time.Now())
+		assert.True(t, ok)
+		ok, _ = s.check(FatalLevel, "msg", time.Now())
+		assert.True(t, ok)
+	}
+}
+
+func TestLoggerSampling(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+	logger.SetSampler(NewSampler(map[stdLogger.Level]SamplingRule{
+		WarnLevel: {Interval: time.Hour, First: 2},
+	}))
+
+	for i := 0; i < 100; i++ {
+		logger.Warnf("retrying %s", "db")
+	}
+	logger.WithField("key", "value").Info("not sampled")
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 3)
+
+	logger.SetSampler(nil)
+	buffer.Reset()
+	logger.WithField("key", "value").Warn("retrying db")
+
+	data := logData{}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &data))
+	assert.Equal(t, "value", data.Data["key"])
+	assert.NotContains(t, data.Data, SuppressedField)
+}
+
+func TestLoggerSamplingReportsSuppressed(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = &JSONFormatter{DisableTTY: true}
+	sampler := NewSampler(map[stdLogger.Level]SamplingRule{
+		WarnLevel: {Interval: time.Hour, First: 1, Thereafter: 5},
+	})
+	logger.SetSampler(sampler)
+
+	for i := 0; i < 6; i++ {
+		logger.WithField("attempt", i).Warn("retrying")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		data := logData{}
+		assert.NoError(t, json.Unmarshal([]byte(lines[1]), &data))
+		assert.Equal(t, 4.0, data.Data[SuppressedField])
+		assert.Equal(t, 5.0, data.Data["attempt"])
+	}
+}
+
+func BenchmarkSamplerDrop(b *testing.B) {
+	logger := New()
+	logger.Out = &bytes.Buffer{}
+	logger.SetSampler(NewSampler(map[stdLogger.Level]SamplingRule{
+		WarnLevel: {Interval: time.Hour, First: 1},
+	}))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logger.Warn("hot loop")
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.22
+// +build go1.22
+
+package slog
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"testing/slogtest"
+
+	"github.com/bdlm/log/v2"
+)
+
+// TestHandlerConformance runs the standard library's handler tests against
+// a logger using the JSON formatter.
+func TestHandlerConformance(t *testing.T) {
+	var buffer bytes.Buffer
+	newHandler := func(t *testing.T) slog.Handler {
+		buffer.Reset()
+		logger := log.New()
+		logger.Out = &buffer
+		logger.Formatter = &log.JSONFormatter{DisableTTY: true, DisableHostname: true, DisableCaller: true}
+		logger.SetLevel(log.DebugLevel)
+		return NewHandler(logger)
+	}
+	result := func(t *testing.T) map[string]any {
+		var entry struct {
+			Time    string         `json:"time"`
+			Level   string         `json:"level"`
+			Message string         `json:"msg"`
+			Data    map[string]any `json:"data"`
+		}
+		if err := json.Unmarshal(buffer.Bytes(), &entry); nil != err {
+			t.Fatal(err)
+		}
+		m := entry.Data
+		if nil == m {
+			m = map[string]any{}
+		}
+		// Entries always have a time, records without one are logged with
+		// the current time.
+		if !strings.HasSuffix(t.Name(), "/zero-time") {
+			m[slog.TimeKey] = entry.Time
+		}
+		m[slog.LevelKey] = entry.Level
+		m[slog.MessageKey] = entry.Message
+		return m
+	}
+	slogtest.Run(t, newHandler, result)
+}
@@ -0,0 +1,10 @@
+// Package slog connects bdlm/log and the standard library's log/slog package
+// so a process can mix both APIs with consistent output.
+//
+// `NewHandler` returns a slog.Handler that writes records to a `*log.Logger`,
+// and `NewLogger` and `Formatter` send the entries of a `*log.Logger` to any
+// slog.Handler. Fields, errors and the caller are preserved in both
+// directions.
+//
+// The package requires Go 1.21 or later.
+package slog
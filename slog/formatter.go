@@ -0,0 +1,93 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+
+	"github.com/bdlm/log/v2"
+)
+
+// Formatter is a log.Formatter that sends entries to a slog.Handler instead
+// of producing output. Levels are mapped with `FromLogLevel`, fields and
+// typed fields become attributes with nested maps as groups, the entry's
+// error is added under `log.ErrorKey` and the caller is used as the record's
+// source.
+//
+// Use `NewLogger` to create a logger that writes only to the handler.
+type Formatter struct {
+	Handler slog.Handler
+}
+
+// NewLogger returns a logger whose entries are sent to handler. The logger's
+// level is log.DebugLevel, the handler decides which entries are kept.
+func NewLogger(handler slog.Handler) *log.Logger {
+	logger := log.New()
+	// Not ioutil.Discard, entries logged to it are not built at all.
+	logger.Out = discard{}
+	logger.Formatter = &Formatter{Handler: handler}
+	logger.Level = log.DebugLevel
+	return logger
+}
+
+type discard struct{}
+
+func (discard) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Format implements log.Formatter. It returns no output.
+func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
+	ctx := entry.Context
+	if nil == ctx {
+		ctx = context.Background()
+	}
+	level := FromLogLevel(entry.Level)
+	if !f.Handler.Enabled(ctx, level) {
+		return nil, nil
+	}
+
+	var pc uintptr
+	if nil != entry.Caller {
+		// Frame.PC is the call instruction, records hold the return
+		// address.
+		pc = entry.Caller.PC + 1
+	}
+	record := slog.NewRecord(entry.Time, level, entry.Message, pc)
+	record.AddAttrs(fieldAttrs(entry.AllData())...)
+	if nil != entry.Err {
+		record.AddAttrs(slog.Any(log.ErrorKey, entry.Err))
+	}
+	return nil, f.Handler.Handle(ctx, record)
+}
+
+// NeedsCaller implements log.CallerFormatter.
+func (f *Formatter) NeedsCaller() (caller, trace bool) {
+	return true, false
+}
+
+// fieldAttrs converts fields to attributes sorted by key, nested maps become
+// groups.
+func fieldAttrs(fields map[string]interface{}) []slog.Attr {
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]slog.Attr, 0, len(keys))
+	for _, k := range keys {
+		switch v := fields[k].(type) {
+		case log.Fields:
+			attrs = append(attrs, slog.Attr{Key: k, Value: slog.GroupValue(fieldAttrs(v)...)})
+		case map[string]interface{}:
+			attrs = append(attrs, slog.Attr{Key: k, Value: slog.GroupValue(fieldAttrs(v)...)})
+		default:
+			attrs = append(attrs, slog.Any(k, v))
+		}
+	}
+	return attrs
+}
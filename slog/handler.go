@@ -0,0 +1,156 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+
+	"github.com/bdlm/log/v2"
+)
+
+// Handler is a slog.Handler that writes records to a `*log.Logger`.
+//
+// Record levels are mapped with `ToLogLevel` and the logger's level decides
+// which records are enabled. Attributes become entry fields, groups become
+// nested `log.Fields` maps, and an error attribute with the key
+// `log.ErrorKey` at the top level becomes the entry's error. The record's
+// source is used as the entry's caller.
+//
+//	logger := log.New()
+//	slog.SetDefault(slog.New(logslog.NewHandler(logger)))
+type Handler struct {
+	logger *log.Logger
+	attrs  []groupAttrs
+	groups []string
+}
+
+// groupAttrs are attributes added by WithAttrs inside the groups that were
+// open at the time.
+type groupAttrs struct {
+	groups []string
+	attrs  []slog.Attr
+}
+
+// NewHandler returns a handler that writes records to logger.
+func NewHandler(logger *log.Logger) *Handler {
+	return &Handler{logger: logger}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.logger.GetLevel() >= ToLogLevel(level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, record slog.Record) error {
+	var err error
+	fields := log.Fields{}
+	for _, ga := range h.attrs {
+		addAttrs(fields, ga.groups, ga.attrs, &err)
+	}
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+	addAttrs(fields, h.groups, attrs, &err)
+
+	entry := h.logger.WithFields(fields)
+	if nil != err {
+		entry = entry.WithError(err)
+	}
+	if nil != ctx {
+		entry = entry.WithContext(ctx)
+	}
+	if !record.Time.IsZero() {
+		entry = entry.WithTime(record.Time)
+	}
+	if 0 != record.PC {
+		frame, _ := runtime.CallersFrames([]uintptr{record.PC}).Next()
+		entry.Caller = &frame
+	}
+
+	entry.Log(ToLogLevel(record.Level), record.Message)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if 0 == len(attrs) {
+		return h
+	}
+	clone := *h
+	clone.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], groupAttrs{groups: h.groups, attrs: attrs})
+	return &clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if "" == name {
+		return h
+	}
+	clone := *h
+	clone.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &clone
+}
+
+// addAttrs adds attributes to fields inside the named groups. Groups without
+// attributes are omitted.
+func addAttrs(fields log.Fields, groups []string, attrs []slog.Attr, err *error) {
+	if 0 == len(attrs) {
+		return
+	}
+	target := fields
+	for _, name := range groups {
+		target = subFields(target, name)
+	}
+	for _, attr := range attrs {
+		addAttr(target, attr, 0 == len(groups), err)
+	}
+}
+
+// addAttr adds an attribute to fields following the slog.Handler rules:
+// empty attributes are ignored, empty groups are omitted and groups without
+// a key are inlined.
+func addAttr(fields log.Fields, attr slog.Attr, top bool, err *error) {
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if slog.KindGroup == attr.Value.Kind() {
+		group := attr.Value.Group()
+		if 0 == len(group) {
+			return
+		}
+		target := fields
+		if "" != attr.Key {
+			target = subFields(fields, attr.Key)
+			top = false
+		}
+		for _, a := range group {
+			addAttr(target, a, top, err)
+		}
+		return
+	}
+	if top && log.ErrorKey == attr.Key {
+		if e, ok := attr.Value.Any().(error); ok {
+			*err = e
+			return
+		}
+	}
+	fields[attr.Key] = attr.Value.Any()
+}
+
+// subFields returns the nested fields stored under key, adding them if
+// needed.
+func subFields(fields log.Fields, key string) log.Fields {
+	sub, ok := fields[key].(log.Fields)
+	if !ok {
+		sub = log.Fields{}
+		fields[key] = sub
+	}
+	return sub
+}
@@ -0,0 +1,56 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"log/slog"
+
+	"github.com/bdlm/log/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// Levels of Trace, Fatal and Panic entries sent to a slog.Handler, slog has
+// no equivalent.
+const (
+	LevelTrace = slog.LevelDebug - 4
+	LevelPanic = slog.LevelError + 4
+	LevelFatal = slog.LevelError + 8
+)
+
+// ToLogLevel maps a slog level to a log level. Levels between the slog
+// levels are rounded down to the less severe level, levels above
+// slog.LevelError map to log.ErrorLevel and levels between LevelTrace and
+// slog.LevelDebug map to log.DebugLevel.
+func ToLogLevel(level slog.Level) stdLogger.Level {
+	switch {
+	case level >= slog.LevelError:
+		return log.ErrorLevel
+	case level >= slog.LevelWarn:
+		return log.WarnLevel
+	case level >= slog.LevelInfo:
+		return log.InfoLevel
+	case level > LevelTrace:
+		return log.DebugLevel
+	}
+	return log.TraceLevel
+}
+
+// FromLogLevel maps a log level to a slog level.
+func FromLogLevel(level stdLogger.Level) slog.Level {
+	switch {
+	case level <= log.FatalLevel:
+		return LevelFatal
+	case level <= log.PanicLevel:
+		return LevelPanic
+	case level <= log.ErrorLevel:
+		return slog.LevelError
+	case level <= log.WarnLevel:
+		return slog.LevelWarn
+	case level <= log.InfoLevel:
+		return slog.LevelInfo
+	case level <= log.DebugLevel:
+		return slog.LevelDebug
+	}
+	return LevelTrace
+}
@@ -0,0 +1,145 @@
+//go:build go1.21
+// +build go1.21
+
+package slog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bdlm/log/v2"
+	"github.com/bdlm/log/v2/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevels(t *testing.T) {
+	assert.Equal(t, log.TraceLevel, ToLogLevel(slog.LevelDebug-4))
+	assert.Equal(t, log.TraceLevel, ToLogLevel(slog.LevelDebug-8))
+	assert.Equal(t, log.DebugLevel, ToLogLevel(slog.LevelDebug-2))
+	assert.Equal(t, log.DebugLevel, ToLogLevel(slog.LevelDebug))
+	assert.Equal(t, log.InfoLevel, ToLogLevel(slog.LevelInfo))
+	assert.Equal(t, log.InfoLevel, ToLogLevel(slog.LevelInfo+2))
+	assert.Equal(t, log.WarnLevel, ToLogLevel(slog.LevelWarn))
+	assert.Equal(t, log.ErrorLevel, ToLogLevel(slog.LevelError))
+	assert.Equal(t, log.ErrorLevel, ToLogLevel(LevelFatal))
+
+	assert.Equal(t, LevelTrace, FromLogLevel(log.TraceLevel))
+	assert.Equal(t, slog.LevelDebug, FromLogLevel(log.DebugLevel))
+	assert.Equal(t, slog.LevelInfo, FromLogLevel(log.InfoLevel))
+	assert.Equal(t, slog.LevelWarn, FromLogLevel(log.WarnLevel))
+	assert.Equal(t, slog.LevelError, FromLogLevel(log.ErrorLevel))
+	assert.Equal(t, LevelPanic, FromLogLevel(log.PanicLevel))
+	assert.Equal(t, LevelFatal, FromLogLevel(log.FatalLevel))
+}
+
+func TestHandler(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	logger.SetLevel(log.InfoLevel)
+	err := errors.New("boom")
+
+	l := slog.New(NewHandler(logger)).With("service", "api").WithGroup("req")
+	l.Debug("dropped")
+	l.Warn("request failed",
+		"method", "GET",
+		slog.Group("user", "id", 42, "name", "alice"),
+		slog.Group("empty"),
+		slog.Duration("elapsed", time.Second),
+	)
+	slog.New(NewHandler(logger)).Error("failed", log.ErrorKey, err)
+
+	entries := hook.AllEntries()
+	if !assert.Len(t, entries, 2) {
+		return
+	}
+
+	entry := entries[0]
+	assert.Equal(t, log.WarnLevel, entry.Level)
+	assert.Equal(t, "request failed", entry.Message)
+	assert.Equal(t, log.Fields{
+		"service": "api",
+		"req": log.Fields{
+			"method":  "GET",
+			"user":    log.Fields{"id": int64(42), "name": "alice"},
+			"elapsed": time.Second,
+		},
+	}, entry.Data)
+	if assert.NotNil(t, entry.Caller) {
+		assert.Equal(t, "slog_test.go", filepath.Base(entry.Caller.File))
+		assert.Contains(t, entry.Caller.Function, "TestHandler")
+	}
+
+	assert.Equal(t, log.ErrorLevel, entries[1].Level)
+	assert.Equal(t, err, entries[1].Err)
+	assert.Empty(t, entries[1].Data)
+}
+
+func TestHandlerContext(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+
+	slog.New(NewHandler(logger)).InfoContext(ctx, "test")
+	if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+		assert.Equal(t, "value", entry.Context.Value(key{}))
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewJSONHandler(&buffer, &slog.HandlerOptions{AddSource: true})
+	logger := NewLogger(handler)
+	err := errors.New("boom")
+
+	logger.
+		WithFields(log.Fields{"service": "api", "user": log.Fields{"id": 42}}).
+		With(log.Int("attempt", 3)).
+		WithError(err).
+		Warn("request failed")
+
+	var record map[string]interface{}
+	if assert.Nil(t, json.Unmarshal(buffer.Bytes(), &record)) {
+		assert.Equal(t, "WARN", record["level"])
+		assert.Equal(t, "request failed", record["msg"])
+		assert.Equal(t, "api", record["service"])
+		assert.Equal(t, map[string]interface{}{"id": 42.0}, record["user"])
+		assert.Equal(t, 3.0, record["attempt"])
+		assert.Equal(t, "boom", record[log.ErrorKey])
+		source, _ := record["source"].(map[string]interface{})
+		assert.Equal(t, "slog_test.go", filepath.Base(source["file"].(string)))
+		assert.Contains(t, source["function"], "TestFormatter")
+	}
+}
+
+func TestFormatterLevel(t *testing.T) {
+	var buffer bytes.Buffer
+	handler := slog.NewTextHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelWarn})
+	logger := NewLogger(handler)
+
+	logger.Info("dropped")
+	assert.Empty(t, buffer.String())
+	logger.Error("kept")
+	assert.Contains(t, buffer.String(), "msg=kept")
+}
+
+func TestRoundTrip(t *testing.T) {
+	// slog -> log -> slog keeps the fields, error and source.
+	var buffer bytes.Buffer
+	logger := NewLogger(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{AddSource: true}))
+	l := slog.New(NewHandler(logger))
+
+	l.Info("test", "k", "v", log.ErrorKey, errors.New("boom"))
+
+	var record map[string]interface{}
+	if assert.Nil(t, json.Unmarshal(buffer.Bytes(), &record)) {
+		assert.Equal(t, "v", record["k"])
+		assert.Equal(t, "boom", record[log.ErrorKey])
+		source, _ := record["source"].(map[string]interface{})
+		assert.Contains(t, source["function"], "TestRoundTrip")
+	}
+}
@@ -4,27 +4,47 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"sync"
 	"text/template"
 )
 
 var (
-	stdTemplate = template.Must(template.New("log").Parse(
-		"{{if .Timestamp}}{{.Timestamp}}{{end}}" +
-			"{{if .Message}} {{.Message}}{{end}}" +
-			"{{if .Level}} {{.LabelLevel}}=\"{{.Level}}\"{{end}}" +
-			"{{$labelData := .LabelData}}" +
-			"{{range $k, $v := .Data}} " +
-			"{{if $labelData}}{{$labelData}}.{{end}}" +
-			"{{$k}}={{$v}}" +
-			"{{end}}" +
-			"{{if .Err}} {{.LabelError}}=\"{{.Err}}\"{{end}}" +
-			"{{if .Caller}} {{.LabelCaller}}=\"{{.Caller}}\"{{end}}" +
-			"{{if .Hostname}} {{.LabelHost}}=\"{{.Hostname}}\"{{end}}" +
-			"{{if .Err}} {{.LabelError}}=\"{{.Err}}\"{{end}}" +
-			"{{range $k, $v := .Trace}} trace.{{$k}}=\"{{$v}}\"{{end}}",
-	))
+	stdTemplate = newStdTemplate(stdKeyOrder)
 )
 
+// stdKeyOrder is the default order of the fields the std formatter writes
+// after the timestamp and message.
+var stdKeyOrder = []FieldLabel{
+	LabelLevel,
+	LabelData,
+	LabelError,
+	LabelCaller,
+	LabelHost,
+	LabelTrace,
+}
+
+// stdFragments holds the std template for each default field written after
+// the message.
+var stdFragments = map[FieldLabel]string{
+	LabelLevel:  "{{if .Level}} {{.LabelLevel}}=\"{{.Level}}\"{{end}}",
+	LabelData:   "{{.DataText}}",
+	LabelError:  "{{if .Err}} {{.LabelError}}=\"{{.Err}}\"{{end}}",
+	LabelCaller: "{{if .Caller}} {{.LabelCaller}}=\"{{.Caller}}\"{{end}}",
+	LabelHost:   "{{if .Hostname}} {{.LabelHost}}=\"{{.Hostname}}\"{{end}}",
+	LabelTrace:  "{{range $k, $v := .Trace}} trace.{{$k}}=\"{{$v}}\"{{end}}",
+}
+
+// newStdTemplate returns the std template writing the default fields after
+// the timestamp and message in order.
+func newStdTemplate(order []FieldLabel) *template.Template {
+	text := "{{if .Timestamp}}{{.Timestamp}}{{end}}" +
+		"{{if .Message}} {{.Message}}{{end}}"
+	for _, label := range order {
+		text += stdFragments[label]
+	}
+	return template.Must(template.New("log").Parse(text))
+}
+
 // StdFormatter formats logs into text.
 type StdFormatter struct {
 	// DataKey allows users to put all the log entry parameters into a
@@ -69,6 +89,26 @@ type StdFormatter struct {
 
 	// TimestampFormat allows a custom timestamp format to be used.
 	TimestampFormat string
+
+	// KeyOrder sets the order of the default fields written after the
+	// timestamp and message, which always come first. Fields that are not
+	// listed follow in the default order: level, data, error, caller, host
+	// and trace.
+	KeyOrder []FieldLabel
+
+	// KeepFieldOrder writes data fields in the order they were added to the
+	// entry instead of sorted by key.
+	KeepFieldOrder bool
+
+	// The template for KeyOrder
+	template *template.Template
+
+	once sync.Once
+}
+
+// NeedsCaller implements CallerFormatter.
+func (f *StdFormatter) NeedsCaller() (caller, trace bool) {
+	return !f.DisableCaller || f.EnableTrace, f.EnableTrace
 }
 
 // Format renders a single log entry
@@ -83,7 +123,8 @@ func (f *StdFormatter) Format(entry *Entry) ([]byte, error) {
 		logLine = &bytes.Buffer{}
 	}
 
-	data := getData(entry, f.FieldMap, f.EscapeHTML, false)
+	caller, trace := f.NeedsCaller()
+	data := getEntryData(entry, f.FieldMap, f.EscapeHTML, false, caller, trace)
 
 	if f.DisableTimestamp {
 		data.Timestamp = ""
@@ -111,11 +152,23 @@ func (f *StdFormatter) Format(entry *Entry) ([]byte, error) {
 
 	for k, v := range data.Data {
 		if e, ok := v.(error); ok {
-			v = e.Error()
+			data.Data[k] = e.Error()
 		}
-		data.Data[k] = escape(v, f.EscapeHTML)
 	}
-	err = stdTemplate.Execute(logLine, data)
+	data.DataKeys = dataKeys(entry, data.Data, data.Typed, f.KeepFieldOrder)
+	dataText, err := appendTextData(nil, data, f.EscapeHTML)
+	if nil != err {
+		return nil, err
+	}
+	data.DataText = string(dataText)
+
+	f.once.Do(func() {
+		f.template = stdTemplate
+		if 0 < len(f.KeyOrder) {
+			f.template = newStdTemplate(keyOrder(f.KeyOrder, stdKeyOrder))
+		}
+	})
+	err = f.template.Execute(logLine, data)
 	if nil != err {
 		return nil, err
 	}
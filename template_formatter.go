@@ -0,0 +1,458 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bdlm/errors/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// TemplateEntry is the view of an entry a `TemplateFormatter` template is
+// executed with.
+type TemplateEntry struct {
+	// Level is the level's name, e.g. "info".
+	Level string
+	// LevelValue is the level.
+	LevelValue stdLogger.Level
+	// Time is the time the entry was logged at.
+	Time time.Time
+	// Timestamp is Time formatted with the formatter's timestamp format.
+	Timestamp string
+	// Message is the message.
+	Message string
+	// Data holds the map and typed fields by key.
+	Data map[string]interface{}
+	// Fields holds the fields sorted by key, or in the order they were added
+	// if `TemplateOptions.KeepFieldOrder` is set.
+	Fields []TemplateField
+	// Err is the entry's error, if any.
+	Err error
+	// Errors is the error chain, the error first followed by the errors it
+	// wraps.
+	Errors []string
+	// Caller is the caller as "file:line function", empty if disabled.
+	Caller string
+	// Trace is the stack trace, empty unless enabled.
+	Trace []string
+	// Host is the hostname, empty if disabled.
+	Host string
+	// Colors holds the TTY colors, empty if colors are disabled.
+	Colors TemplateColors
+}
+
+// TemplateField is a field of a `TemplateEntry`.
+type TemplateField struct {
+	Key   string
+	Value interface{}
+}
+
+// TemplateColors holds the escape sequences of the theme colors for an
+// entry. Every color is empty if colors are disabled.
+type TemplateColors struct {
+	Caller    string
+	DataLabel string
+	DataValue string
+	Err       string
+	Hostname  string
+	Level     string
+	Reset     string
+	Timestamp string
+	Trace     string
+}
+
+// TemplateOptions configures a `TemplateFormatter`.
+type TemplateOptions struct {
+	// DisableCaller disables caller data output.
+	DisableCaller bool
+
+	// DisableHostname disables hostname output.
+	DisableHostname bool
+
+	// EnableTrace enables full backtrace output.
+	EnableTrace bool
+
+	// DisableColors disables colors.
+	DisableColors bool
+
+	// ForceColors enables colors if the output is not a terminal.
+	ForceColors bool
+
+	// Theme sets the colors. If nil, the color variables such as
+	// `DEFAULTColor` are used.
+	Theme *Theme
+
+	// ColorProfile sets the colors the terminal can display. By default it
+	// is detected from the environment, see `DetectColorProfile`.
+	ColorProfile ColorProfile
+
+	// TimestampFormat sets the format of `TemplateEntry.Timestamp`.
+	TimestampFormat string
+
+	// KeepFieldOrder orders `TemplateEntry.Fields` in the order the fields
+	// were added to the entry instead of by key.
+	KeepFieldOrder bool
+
+	// Funcs are added to the template's functions and can override them.
+	Funcs template.FuncMap
+}
+
+// TemplateFormatter formats entries with a user template, to match an
+// existing log format without writing a `Formatter`.
+//
+// The template is executed with a `*TemplateEntry` and has these functions
+// in addition to the `text/template` ones:
+//
+//	pad n s          s padded with spaces to n characters
+//	padLeft n s      s right aligned in n characters
+//	json v           v JSON encoded
+//	formatTime l t   t formatted with the layout l
+//	color c s        s wrapped in the color sequence c and a reset
+//	upper s          s in upper case
+//	lower s          s in lower case
+//
+// A newline is appended to the output if the template doesn't end with one.
+type TemplateFormatter struct {
+	opts     TemplateOptions
+	template *template.Template
+
+	// Flag noting whether the logger's output is to a terminal
+	isTerminal bool
+
+	// Flag noting whether CLICOLOR_FORCE forces colors
+	forceColor bool
+
+	// The color profile detected from the environment
+	colorProfile ColorProfile
+
+	once sync.Once
+}
+
+// NewTemplateFormatter returns a formatter executing the template text for
+// every entry. Template syntax errors and references to fields a
+// `TemplateEntry` doesn't have are reported here rather than when logging.
+// Errors that depend on the entry, such as an index out of range, are
+// returned by `Format`.
+//
+//	formatter, err := log.NewTemplateFormatter(
+//		`{{formatTime "15:04:05" .Time}} [{{upper .Level | padLeft 5}}] {{.Message}}`,
+//		log.TemplateOptions{},
+//	)
+func NewTemplateFormatter(text string, opts TemplateOptions) (*TemplateFormatter, error) {
+	funcs := templateFuncs()
+	for name, fn := range opts.Funcs {
+		funcs[name] = fn
+	}
+	tmpl, err := template.New("log").Funcs(funcs).Parse(text)
+	if nil != err {
+		return nil, err
+	}
+
+	// Parsing doesn't know the type of dot, check field references against
+	// TemplateEntry here instead of when logging. The template is not
+	// executed, user functions may have side effects.
+	for _, t := range tmpl.Templates() {
+		root := reflect.TypeOf(&TemplateEntry{})
+		if "log" != t.Name() {
+			// Defined templates are executed with any value.
+			root = nil
+		}
+		checker := &fieldChecker{tree: t.Tree, root: root}
+		if err := checker.walk(t.Tree.Root, root); nil != err {
+			return nil, err
+		}
+	}
+
+	return &TemplateFormatter{opts: opts, template: tmpl}, nil
+}
+
+func (f *TemplateFormatter) init(entry *Entry) {
+	if nil != entry.Logger {
+		f.isTerminal = checkIfTerminal(entry.Logger.Out)
+	}
+	f.forceColor = forceColor()
+	f.colorProfile = outputColorProfile(f.isTerminal)
+}
+
+// NeedsCaller implements CallerFormatter.
+func (f *TemplateFormatter) NeedsCaller() (caller, trace bool) {
+	return !f.opts.DisableCaller, f.opts.EnableTrace
+}
+
+// Format renders a single log entry.
+func (f *TemplateFormatter) Format(entry *Entry) ([]byte, error) {
+	f.once.Do(func() { f.init(entry) })
+
+	view := &TemplateEntry{
+		Level:      LevelString(entry.Level),
+		LevelValue: entry.Level,
+		Time:       entry.Time,
+		Message:    entry.Message,
+		Data:       entry.AllData(),
+		Err:        entry.Err,
+		Trace:      []string{},
+	}
+	if "" != f.opts.TimestampFormat {
+		view.Timestamp = entry.Time.Format(f.opts.TimestampFormat)
+	} else {
+		view.Timestamp = entry.Time.Format(defaultTimestampFormat)
+	}
+	keys := dataKeys(entry, view.Data, nil, f.opts.KeepFieldOrder)
+	view.Fields = make([]TemplateField, 0, len(keys))
+	for _, k := range keys {
+		view.Fields = append(view.Fields, TemplateField{Key: k, Value: view.Data[k]})
+	}
+	for e := entry.Err; nil != e; e = errors.Unwrap(e) {
+		view.Errors = append(view.Errors, e.Error())
+	}
+	if !f.opts.DisableCaller {
+		view.Caller = entry.callerString()
+	}
+	if f.opts.EnableTrace {
+		view.Trace = entry.stackTrace()
+	}
+	if !f.opts.DisableHostname {
+		view.Host = entry.Host
+		if "" == view.Host {
+			view.Host = localHostname()
+		}
+	}
+	if (f.opts.ForceColors || f.isTerminal || f.forceColor) && !f.opts.DisableColors {
+		profile := f.opts.ColorProfile
+		if ColorsAuto == profile {
+			profile = f.colorProfile
+		}
+		view.Colors = TemplateColors(getColors(f.opts.Theme, profile, entry.Level))
+	}
+
+	var buf *bytes.Buffer
+	if nil != entry.Buffer {
+		buf = entry.Buffer
+	} else {
+		buf = &bytes.Buffer{}
+	}
+	if err := f.template.Execute(buf, view); nil != err {
+		return nil, err
+	}
+	if 0 == buf.Len() || '\n' != buf.Bytes()[buf.Len()-1] {
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// templateFuncs returns the functions available to `TemplateFormatter`
+// templates.
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"pad": func(width int, v interface{}) string {
+			s := fmt.Sprint(v)
+			if n := width - utf8.RuneCountInString(s); 0 < n {
+				return s + strings.Repeat(" ", n)
+			}
+			return s
+		},
+		"padLeft": func(width int, v interface{}) string {
+			s := fmt.Sprint(v)
+			if n := width - utf8.RuneCountInString(s); 0 < n {
+				return strings.Repeat(" ", n) + s
+			}
+			return s
+		},
+		"json": func(v interface{}) (string, error) {
+			if e, ok := v.(error); ok {
+				if _, ok := v.(json.Marshaler); !ok {
+					v = e.Error()
+				}
+			}
+			byts, err := json.Marshal(v)
+			return string(byts), err
+		},
+		"formatTime": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"color": func(color string, v interface{}) string {
+			if "" == color {
+				return fmt.Sprint(v)
+			}
+			return color + fmt.Sprint(v) + ResetColor
+		},
+		"upper": func(v interface{}) string {
+			return strings.ToUpper(fmt.Sprint(v))
+		},
+		"lower": func(v interface{}) string {
+			return strings.ToLower(fmt.Sprint(v))
+		},
+	}
+}
+
+// fieldChecker reports references to fields a template's values don't have.
+// Types that are only known when the template is executed, such as map and
+// interface values and variables other than `$`, are not checked.
+type fieldChecker struct {
+	tree *parse.Tree
+	// root is the type of `$`, nil if unknown.
+	root reflect.Type
+}
+
+// walk checks node with dot of type dot, nil if unknown.
+func (c *fieldChecker) walk(node parse.Node, dot reflect.Type) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if nil == n {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.walk(child, dot); nil != err {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		_, err := c.pipe(n.Pipe, dot)
+		return err
+	case *parse.IfNode:
+		return c.branch(&n.BranchNode, dot, false)
+	case *parse.RangeNode:
+		return c.branch(&n.BranchNode, dot, true)
+	case *parse.WithNode:
+		return c.branch(&n.BranchNode, dot, false)
+	case *parse.TemplateNode:
+		_, err := c.pipe(n.Pipe, dot)
+		return err
+	}
+	return nil
+}
+
+// branch checks an if, range or with block. The block of a with or range is
+// executed with the pipeline's value or its elements as dot.
+func (c *fieldChecker) branch(n *parse.BranchNode, dot reflect.Type, elem bool) error {
+	t, err := c.pipe(n.Pipe, dot)
+	if nil != err {
+		return err
+	}
+	inner := dot
+	if parse.NodeWith == n.Type() {
+		inner = t
+	} else if elem {
+		inner = elemType(t)
+	}
+	if err := c.walk(n.List, inner); nil != err {
+		return err
+	}
+	return c.walk(n.ElseList, dot)
+}
+
+// pipe checks a pipeline and returns the type of its value, nil if unknown.
+func (c *fieldChecker) pipe(pipe *parse.PipeNode, dot reflect.Type) (reflect.Type, error) {
+	if nil == pipe {
+		return nil, nil
+	}
+	var t reflect.Type
+	for i, cmd := range pipe.Cmds {
+		t = nil
+		for _, arg := range cmd.Args {
+			argType, err := c.arg(arg, dot)
+			if nil != err {
+				return nil, err
+			}
+			if 0 == i && 1 == len(cmd.Args) {
+				t = argType
+			}
+		}
+	}
+	return t, nil
+}
+
+// arg checks a command argument and returns its type, nil if unknown.
+func (c *fieldChecker) arg(node parse.Node, dot reflect.Type) (reflect.Type, error) {
+	switch n := node.(type) {
+	case *parse.DotNode:
+		return dot, nil
+	case *parse.FieldNode:
+		return c.fields(n, dot, n.Ident)
+	case *parse.VariableNode:
+		if "$" != n.Ident[0] {
+			return nil, nil
+		}
+		return c.fields(n, c.root, n.Ident[1:])
+	case *parse.ChainNode:
+		t, err := c.arg(n.Node, dot)
+		if nil != err {
+			return nil, err
+		}
+		return c.fields(n, t, n.Field)
+	case *parse.PipeNode:
+		return c.pipe(n, dot)
+	}
+	return nil, nil
+}
+
+// fields resolves a chain of field and method names on t.
+func (c *fieldChecker) fields(node parse.Node, t reflect.Type, names []string) (reflect.Type, error) {
+	for _, name := range names {
+		if nil == t {
+			return nil, nil
+		}
+		method, ok := t.MethodByName(name)
+		if !ok && reflect.Struct == t.Kind() {
+			// Methods with a pointer receiver can be called on fields.
+			method, ok = reflect.PtrTo(t).MethodByName(name)
+		}
+		if ok {
+			t = nil
+			if 0 < method.Type.NumOut() {
+				t = method.Type.Out(0)
+			}
+			continue
+		}
+		if reflect.Ptr == t.Kind() {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := t.FieldByName(name)
+			if !ok || "" != field.PkgPath {
+				return nil, c.errorf(node, "can't evaluate field %s in type %s", name, t)
+			}
+			t = field.Type
+		case reflect.Map:
+			t = t.Elem()
+		case reflect.Interface:
+			t = nil
+		default:
+			return nil, c.errorf(node, "can't evaluate field %s in type %s", name, t)
+		}
+		if nil != t && reflect.Interface == t.Kind() && 0 == t.NumMethod() {
+			t = nil
+		}
+	}
+	return t, nil
+}
+
+func (c *fieldChecker) errorf(node parse.Node, format string, args ...interface{}) error {
+	location, context := c.tree.ErrorContext(node)
+	return fmt.Errorf("template: %s: at <%s>: %s", location, context, fmt.Sprintf(format, args...))
+}
+
+// elemType returns the type of the elements range iterates over, nil if
+// unknown.
+func elemType(t reflect.Type) reflect.Type {
+	if nil == t {
+		return nil
+	}
+	if reflect.Ptr == t.Kind() {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Array, reflect.Chan, reflect.Map, reflect.Slice:
+		return t.Elem()
+	}
+	return nil
+}
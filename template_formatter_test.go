@@ -0,0 +1,157 @@
+package log
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/bdlm/errors/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateFormatter(t *testing.T) {
+	formatter, err := NewTemplateFormatter(
+		`{{formatTime "2006-01-02 15:04:05" .Time}} [{{upper .Level | padLeft 5}}] {{pad 8 .Host}}|{{.Message}}`+
+			`{{range .Fields}} {{.Key}}={{json .Value}}{{end}}`+
+			`{{range .Errors}} error={{json .}}{{end}}`,
+		TemplateOptions{DisableCaller: true},
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = formatter
+	entry := logger.
+		WithFields(Fields{"user": "alice", "count": 3}).
+		With(Bool("ok", true)).
+		WithError(fmt.Errorf("wrapped: %w", errors.New("disk full")))
+	entry.Host = "web1"
+	entry.Time = time.Date(2018, 8, 17, 18, 32, 30, 0, time.UTC)
+	entry.Warn("request failed")
+
+	assert.Equal(t,
+		`2018-08-17 18:32:30 [ WARN] web1    |request failed count=3 ok=true user="alice"`+
+			` error="wrapped: disk full" error="disk full"`+"\n",
+		buffer.String())
+}
+
+func TestTemplateFormatterView(t *testing.T) {
+	formatter, err := NewTemplateFormatter(
+		`{{.LevelValue}} {{.Timestamp}} {{index .Data "user"}} {{.Caller}} {{len .Trace}} {{.Host}}`+"\n",
+		TemplateOptions{
+			DisableHostname: true,
+			EnableTrace:     true,
+			TimestampFormat: time.Kitchen,
+		},
+	)
+	if !assert.Nil(t, err) {
+		return
+	}
+	var buffer bytes.Buffer
+	logger := New()
+	logger.Out = &buffer
+	logger.Formatter = formatter
+	logger.WithField("user", "bob").Info("hi")
+
+	fields := strings.Fields(buffer.String())
+	if assert.Len(t, fields, 6, buffer.String()) {
+		assert.Equal(t, "40", fields[0])
+		_, err := time.Parse(time.Kitchen, fields[1])
+		assert.Nil(t, err)
+		assert.Equal(t, "bob", fields[2])
+		assert.Contains(t, fields[3], "template_formatter_test.go")
+		assert.NotEqual(t, "0", fields[5])
+	}
+	assert.True(t, strings.HasSuffix(buffer.String(), " \n"), buffer.String())
+}
+
+func TestTemplateFormatterColors(t *testing.T) {
+	text := `{{color .Colors.Level .Level}} {{.Message}}`
+	colored, err := NewTemplateFormatter(text, TemplateOptions{
+		ForceColors:  true,
+		Theme:        HighContrastTheme(),
+		ColorProfile: Colors16,
+	})
+	assert.Nil(t, err)
+	plain, err := NewTemplateFormatter(text, TemplateOptions{})
+	assert.Nil(t, err)
+
+	entry := NewEntry(New())
+	entry.Level = ErrorLevel
+	entry.Message = "boom"
+
+	byts, err := colored.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "\033[91merror\033[0m boom\n", string(byts))
+
+	byts, err = plain.Format(entry)
+	assert.Nil(t, err)
+	assert.Equal(t, "error boom\n", string(byts))
+}
+
+func TestTemplateFormatterErrors(t *testing.T) {
+	for _, text := range []string{
+		`{{.Message`,
+		`{{.Mesage}}`,
+		`{{unknown .Message}}`,
+		`{{$.Mesage}}`,
+		`{{.Message.Text}}`,
+		`{{.Colors.Bold}}`,
+		`{{range .Fields}}{{.Name}}{{end}}`,
+		`{{with .Colors}}{{.Bold}}{{end}}`,
+		`{{range .Trace}}{{$.Stack}}{{end}}`,
+	} {
+		_, err := NewTemplateFormatter(text, TemplateOptions{})
+		assert.NotNil(t, err, text)
+	}
+
+	formatter, err := NewTemplateFormatter(`{{shout .Message}}`, TemplateOptions{
+		Funcs: template.FuncMap{
+			"shout": func(s string) string { return strings.ToUpper(s) + "!" },
+		},
+	})
+	if assert.Nil(t, err) {
+		entry := NewEntry(New())
+		entry.Message = "hi"
+		byts, err := formatter.Format(entry)
+		assert.Nil(t, err)
+		assert.Equal(t, "HI!\n", string(byts))
+	}
+}
+
+func TestTemplateFormatterValidation(t *testing.T) {
+	// Valid templates that fail on an empty entry are not rejected, and
+	// user functions are not called when the formatter is created.
+	called := false
+	_, err := NewTemplateFormatter(
+		`{{index .Trace 0}} {{(index .Fields 0).Key}} {{.Data.user.name}} {{track .Message}}`+
+			`{{range .Fields}}{{.Value.Anything}}{{end}}{{with .Err}}{{.Error}}{{end}}`+
+			`{{.Time.Format "15"}}{{define "x"}}{{.Whatever}}{{end}}`,
+		TemplateOptions{
+			EnableTrace: true,
+			Funcs: template.FuncMap{
+				"track": func(s string) string {
+					called = true
+					return s
+				},
+			},
+		},
+	)
+	assert.Nil(t, err)
+	assert.False(t, called)
+
+	formatter, err := NewTemplateFormatter(`{{index .Trace 0}}`, TemplateOptions{EnableTrace: true})
+	if assert.Nil(t, err) {
+		entry := NewEntry(New())
+		entry.Stack = []string{"main.go:1 main.main"}
+		byts, err := formatter.Format(entry)
+		assert.Nil(t, err)
+		assert.Equal(t, "main.go:1 main.main\n", string(byts))
+	}
+}
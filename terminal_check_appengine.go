@@ -9,3 +9,7 @@ import (
 func checkIfTerminal(w io.Writer) bool {
 	return true
 }
+
+func terminalWidth(w io.Writer) int {
+	return 0
+}
@@ -17,3 +17,14 @@ func checkIfTerminal(w io.Writer) bool {
 		return false
 	}
 }
+
+// terminalWidth returns the width of the terminal w writes to, or 0 if it
+// isn't a terminal.
+func terminalWidth(w io.Writer) int {
+	if v, ok := w.(*os.File); ok {
+		if width, _, err := terminal.GetSize(int(v.Fd())); nil == err {
+			return width
+		}
+	}
+	return 0
+}
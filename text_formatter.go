@@ -6,23 +6,25 @@ import (
 	"strings"
 	"sync"
 	"text/template"
+	"unicode/utf8"
 
 	"github.com/bdlm/errors/v2"
+	stdLogger "github.com/bdlm/std/v2/logger"
 )
 
 var (
 	termTemplate = template.Must(template.New("tty").Parse(
 		"{{$color := .Color}}{{$caller := .Caller}}" +
 			// Level
-			"{{$color.Level}}{{printf \"%5s\" .Level}}{{$color.Reset}}" +
+			"{{$color.Level}}{{printf \"%*s\" .LevelWidth .Level}}{{$color.Reset}}" +
 			// Hostname
 			"{{if .Hostname}} {{$color.Hostname}}{{.Hostname}}{{$color.Reset}}{{end}} " +
 			// Message
 			"{{printf \"%s\" .Message}}" +
 			// Data fields
-			"{{if .Data}}\n   {{$color.Level}}⇢{{$color.Reset}} {{range $k, $v := .Data}}" +
-			" {{$color.DataLabel}}{{$k}}{{$color.Reset}}={{$color.DataValue}}{{$v}}{{$color.Reset}}" +
-			"{{end}}{{end}}" +
+			"{{range $i, $line := .DataLines}}" +
+			"{{if eq $i 0}}\n   {{$color.Level}}⇢{{$color.Reset}} {{else}}\n     {{end}}{{$line}}" +
+			"{{end}}" +
 			// Caller
 			"{{if and (.Caller) (not .Trace)}}" +
 			"\n   {{$color.Level}}⇢{{$color.Reset}}  {{$color.Caller}}{{.Caller}}{{$color.Reset}}" +
@@ -39,26 +41,31 @@ var (
 			"{{if .Timestamp}}\n   {{$color.Level}}⇢{{$color.Reset}}  {{$color.Timestamp}}{{.Timestamp}}{{$color.Reset}}{{end}}\n",
 	))
 	//\n   {{$color}}⇢\033[0m  {{if eq $v $caller}}\033[38;5;28m{{else}}\033[38;5;240m{{end}}#{{$k}} {{$v}}\033[0m{{end}}
-	textTemplate = template.Must(template.New("log").Parse(
-		// Timestamp
-		"{{if .Timestamp}} {{.LabelTime}}=\"{{.Timestamp}}\"{{end}} " +
-			// Level
-			"{{.LabelLevel}}=\"{{.Level}}\"" +
-			// Message
-			"{{if .Message}} {{.LabelMsg}}={{.Message}}{{end}}" +
-			// Error
-			"{{if .Err}} {{.LabelError}}=\"{{(printf \"%-v\" .Err)}}\"{{end}}" +
-			// Data fields
-			"{{$labelData := .LabelData}}{{range $k, $v := .Data}} {{if $labelData}}{{$labelData}}.{{end}}{{$k}}={{$v}}{{end}}" +
-			// Caller
-			"{{if .Caller}} {{.LabelCaller}}=\"{{.Caller}}\"{{end}}" +
-			// Hostname
-			"{{if .Hostname}} {{.LabelHost}}=\"{{.Hostname}}\"{{end}}" +
-			// Trace
-			"{{range $k, $v := .Trace}} trace.{{$k}}=\"{{$v}}\"{{end}}",
-	))
+	textTemplate = newTextTemplate(defaultKeyOrder)
 )
 
+// textFragments holds the text template for each default field.
+var textFragments = map[FieldLabel]string{
+	LabelTime:   "{{if .Timestamp}} {{.LabelTime}}=\"{{.Timestamp}}\"{{end}}",
+	LabelLevel:  " {{.LabelLevel}}=\"{{.Level}}\"",
+	LabelMsg:    "{{if .Message}} {{.LabelMsg}}={{.Message}}{{end}}",
+	LabelError:  "{{if .Err}} {{.LabelError}}=\"{{(printf \"%-v\" .Err)}}\"{{end}}",
+	LabelData:   "{{.DataText}}",
+	LabelCaller: "{{if .Caller}} {{.LabelCaller}}=\"{{.Caller}}\"{{end}}",
+	LabelHost:   "{{if .Hostname}} {{.LabelHost}}=\"{{.Hostname}}\"{{end}}",
+	LabelTrace:  "{{range $k, $v := .Trace}} trace.{{$k}}=\"{{$v}}\"{{end}}",
+}
+
+// newTextTemplate returns the text template writing the default fields in
+// order.
+func newTextTemplate(order []FieldLabel) *template.Template {
+	text := ""
+	for _, label := range order {
+		text += textFragments[label]
+	}
+	return template.Must(template.New("log").Parse(text))
+}
+
 // TextFormatter formats logs into text.
 type TextFormatter struct {
 	// DataKey allows users to put all the log entry parameters into a
@@ -110,9 +117,48 @@ type TextFormatter struct {
 	// TimestampFormat allows a custom timestamp format to be used.
 	TimestampFormat string
 
+	// KeyOrder sets the order of the default fields in non-TTY output, e.g.
+	// `[]FieldLabel{LabelTime, LabelLevel, LabelMsg}`. Fields that are not
+	// listed follow in the default order: time, level, msg, error, data,
+	// caller, host and trace.
+	KeyOrder []FieldLabel
+
+	// KeepFieldOrder writes data fields in the order they were added to the
+	// entry instead of sorted by key.
+	KeepFieldOrder bool
+
+	// Width is the TTY line width data fields and messages are wrapped at.
+	// If 0, the width of the terminal is used, if negative or if the output
+	// is not a terminal, lines are not wrapped.
+	Width int
+
+	// Compact truncates long TTY data values with an ellipsis.
+	Compact bool
+
+	// CompactValueWidth is the width data values are truncated to in
+	// compact mode, 32 if 0.
+	CompactValueWidth int
+
+	// Theme sets the TTY colors, e.g. `LightTheme()`. If nil, the color
+	// variables such as `DEFAULTColor` are used.
+	Theme *Theme
+
+	// ColorProfile sets the colors the terminal can display. By default it
+	// is detected from the environment, see `DetectColorProfile`.
+	ColorProfile ColorProfile
+
 	// Flag noting whether the logger's out is to a terminal
 	isTerminal bool
 
+	// Flag noting whether CLICOLOR_FORCE forces TTY formatted output
+	forceColor bool
+
+	// The color profile detected from the environment
+	colorProfile ColorProfile
+
+	// The non-TTY template for KeyOrder
+	template *template.Template
+
 	sync.Once
 }
 
@@ -120,6 +166,26 @@ func (f *TextFormatter) init(entry *Entry) {
 	if entry.Logger != nil {
 		f.isTerminal = checkIfTerminal(entry.Logger.Out)
 	}
+	f.forceColor = forceColor()
+	f.colorProfile = outputColorProfile(f.isTerminal)
+	f.template = textTemplate
+	if 0 < len(f.KeyOrder) {
+		f.template = newTextTemplate(keyOrder(f.KeyOrder, defaultKeyOrder))
+	}
+}
+
+// colors returns the TTY colors of an entry at a level.
+func (f *TextFormatter) colors(level stdLogger.Level) colors {
+	profile := f.ColorProfile
+	if ColorsAuto == profile {
+		profile = f.colorProfile
+	}
+	return getColors(f.Theme, profile, level)
+}
+
+// NeedsCaller implements CallerFormatter.
+func (f *TextFormatter) NeedsCaller() (caller, trace bool) {
+	return !f.DisableCaller, f.EnableTrace
 }
 
 // Format renders a single log entry
@@ -136,8 +202,12 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 
 	f.Do(func() { f.init(entry) })
 
-	isTTY := (f.ForceTTY || f.isTerminal) && !f.DisableTTY
-	data := getData(entry, f.FieldMap, f.EscapeHTML, isTTY)
+	isTTY := (f.ForceTTY || f.isTerminal || f.forceColor) && !f.DisableTTY
+	caller, trace := f.NeedsCaller()
+	data := getEntryData(entry, f.FieldMap, f.EscapeHTML, isTTY, caller, trace)
+	if isTTY {
+		data.Color = f.colors(entry.Level)
+	}
 
 	if f.DisableTimestamp {
 		data.Timestamp = ""
@@ -162,6 +232,8 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 		}
 	}
 
+	data.DataKeys = dataKeys(entry, data.Data, data.Typed, f.KeepFieldOrder)
+
 	if isTTY {
 		for k, v := range data.Data {
 			switch tv := v.(type) {
@@ -171,17 +243,134 @@ func (f *TextFormatter) Format(entry *Entry) ([]byte, error) {
 				data.Data[k] = v
 			}
 		}
+		width := f.Width
+		if 0 == width && f.isTerminal && nil != entry.Logger {
+			width = terminalWidth(entry.Logger.Out)
+		}
+		f.layout(data, width)
 		err = termTemplate.Execute(logLine, data)
 	} else {
-		for k, v := range data.Data {
-			data.Data[k] = escape(v, f.EscapeHTML)
+		var dataText []byte
+		if dataText, err = appendTextData(nil, data, f.EscapeHTML); nil != err {
+			return nil, err
 		}
+		data.DataText = string(dataText)
 		data.Message = escape(data.Message, f.EscapeHTML)
-		err = textTemplate.Execute(logLine, data)
+		err = f.template.Execute(logLine, data)
 	}
 	if nil != err {
 		return nil, err
 	}
 
+	// Leading spaces pad TTY levels.
+	if isTTY {
+		return append([]byte(strings.TrimRight(logLine.String(), " \n")), '\n'), nil
+	}
 	return append([]byte(strings.Trim(logLine.String(), " \n")), '\n'), nil
 }
+
+// ttyGutterWidth is the width of the "   ⇢ " gutter TTY data lines start
+// with.
+const ttyGutterWidth = 5
+
+// defaultCompactValueWidth is the width data values are truncated to in
+// compact mode.
+const defaultCompactValueWidth = 32
+
+// layout lays out the level, message and data fields of a TTY entry. Levels
+// are padded to the longest level name so messages line up. If width is
+// positive, data fields are wrapped under the gutter and messages under
+// their first line.
+func (f *TextFormatter) layout(data *logData, width int) {
+	data.LevelWidth = getLevels().nameWidth
+	if data.LevelWidth < 5 {
+		data.LevelWidth = 5
+	}
+
+	maxValueWidth := f.CompactValueWidth
+	if 0 >= maxValueWidth {
+		maxValueWidth = defaultCompactValueWidth
+	}
+	line := &strings.Builder{}
+	lineWidth := 0
+	for _, k := range data.DataKeys {
+		var value string
+		if i := typedIndex(data.Typed, k); 0 <= i {
+			value = string(data.Typed[i].appendTTY(nil))
+		} else {
+			value = fmt.Sprint(data.Data[k])
+		}
+		if f.Compact {
+			value = truncateValue(value, maxValueWidth)
+		}
+		fieldWidth := 1 + utf8.RuneCountInString(k) + 1 + utf8.RuneCountInString(value)
+		if 0 < lineWidth && 0 < width && ttyGutterWidth+lineWidth+fieldWidth > width {
+			data.DataLines = append(data.DataLines, line.String())
+			line.Reset()
+			lineWidth = 0
+		}
+		line.WriteString(" " + data.Color.DataLabel + k + data.Color.Reset +
+			"=" + data.Color.DataValue + value + data.Color.Reset)
+		lineWidth += fieldWidth
+	}
+	if 0 < lineWidth {
+		data.DataLines = append(data.DataLines, line.String())
+	}
+
+	if 0 < width {
+		column := data.LevelWidth + 1
+		if "" != data.Hostname {
+			column += utf8.RuneCountInString(data.Hostname) + 1
+		}
+		// Messages far right of the level are wrapped under the gutter.
+		if width-column < 20 {
+			column = ttyGutterWidth + 1
+		}
+		data.Message = wrapText(data.Message, width-column, "\n"+strings.Repeat(" ", column))
+	}
+}
+
+// truncateValue truncates a value longer than width with an ellipsis,
+// keeping the closing quote of a quoted string.
+func truncateValue(value string, width int) string {
+	if width < 4 {
+		width = 4
+	}
+	runes := []rune(value)
+	if len(runes) <= width {
+		return value
+	}
+	if 2 <= len(runes) && '"' == runes[0] && '"' == runes[len(runes)-1] {
+		return string(runes[:width-2]) + `…"`
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+// wrapText wraps text at spaces so lines are at most width wide, words
+// longer than width are not broken. Lines are joined with sep.
+func wrapText(text string, width int, sep string) string {
+	if 0 >= width || utf8.RuneCountInString(text) <= width && !strings.Contains(text, "\n") {
+		return text
+	}
+	lines := []string{}
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := &strings.Builder{}
+		lineWidth := 0
+		for _, word := range strings.Split(paragraph, " ") {
+			wordWidth := utf8.RuneCountInString(word)
+			if 0 < lineWidth && lineWidth+1+wordWidth > width {
+				lines = append(lines, line.String())
+				line.Reset()
+				lineWidth = 0
+			}
+			if 0 < lineWidth {
+				line.WriteByte(' ')
+				lineWidth++
+			}
+			line.WriteString(word)
+			lineWidth += wordWidth
+		}
+		lines = append(lines, line.String())
+	}
+	return strings.Join(lines, sep)
+}
@@ -214,3 +214,82 @@ func TestTextFormatterFieldMap(t *testing.T) {
 		string(b),
 		"Formatted output doesn't respect FieldMap")
 }
+
+func TestTextFormatterLayout(t *testing.T) {
+	defer newStd()
+
+	entry := &Entry{
+		Logger:  New(),
+		Message: "the walrus are attacking the cattle on the beach again",
+		Level:   WarnLevel,
+		Data: Fields{
+			"animal":   "walrus",
+			"count":    100,
+			"location": "beach",
+			"note":     "a very long note about the walrus that goes on and on",
+		},
+	}
+	format := func(f *TextFormatter) []string {
+		b, err := f.Format(entry)
+		assert.Nil(t, err)
+		return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	}
+	newFormatter := func() *TextFormatter {
+		return &TextFormatter{
+			ForceTTY:         true,
+			ColorProfile:     ColorsNone,
+			DisableCaller:    true,
+			DisableHostname:  true,
+			DisableTimestamp: true,
+		}
+	}
+
+	// Without a width nothing is wrapped.
+	lines := format(newFormatter())
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, " warn "+entry.Message, lines[0])
+		assert.Equal(t, `   ⇢  animal="walrus" count=100 location="beach" note="a very long note about the walrus that goes on and on"`, lines[1])
+	}
+
+	f := newFormatter()
+	f.Width = 40
+	lines = format(f)
+	assert.Equal(t, []string{
+		" warn the walrus are attacking the",
+		"      cattle on the beach again",
+		`   ⇢  animal="walrus" count=100`,
+		`      location="beach"`,
+		`      note="a very long note about the walrus that goes on and on"`,
+	}, lines)
+	for _, line := range lines[:4] {
+		assert.True(t, len([]rune(line)) <= 40, line)
+	}
+
+	f = newFormatter()
+	f.Width = 80
+	f.Compact = true
+	f.CompactValueWidth = 16
+	lines = format(f)
+	assert.Equal(t, []string{
+		" warn the walrus are attacking the cattle on the beach again",
+		`   ⇢  animal="walrus" count=100 location="beach" note="a very long n…"`,
+	}, lines)
+
+	// Levels are padded to the longest registered level name.
+	defer registerNotice(t)()
+	lines = format(newFormatter())
+	assert.Equal(t, "  warn "+entry.Message, lines[0])
+}
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(t, "short", wrapText("short", 10, "\n"))
+	assert.Equal(t, "a b\nc d", wrapText("a b c d", 3, "\n"))
+	assert.Equal(t, "a\nlongword\nb", wrapText("a longword b", 3, "\n"))
+	assert.Equal(t, "one\n  two", wrapText("one\ntwo", 10, "\n  "))
+	assert.Equal(t, "no wrap", wrapText("no wrap", 0, "\n"))
+
+	assert.Equal(t, "short", truncateValue("short", 10))
+	assert.Equal(t, "abcdefg…", truncateValue("abcdefghijk", 8))
+	assert.Equal(t, `"abcde…"`, truncateValue(`"abcdefghijk"`, 8))
+	assert.Equal(t, "héllo w…", truncateValue("héllo wörld", 8))
+}
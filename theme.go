@@ -0,0 +1,162 @@
+package log
+
+import (
+	stdLogger "github.com/bdlm/std/v2/logger"
+)
+
+// Theme is a TTY color palette. Themes are set per formatter, see
+// `TextFormatter.Theme` and `JSONFormatter.Theme`, and are rendered for the
+// formatter's color profile.
+type Theme struct {
+	// Levels holds the colors of the level names and the line gutter, by
+	// level. Levels without a color use the color registered with
+	// `RegisterLevel` or Default.
+	Levels map[stdLogger.Level]Color
+
+	// Default is the color of levels without a color.
+	Default Color
+
+	// Caller is the caller color.
+	Caller Color
+	// DataLabel is the data label color.
+	DataLabel Color
+	// DataValue is the data value color.
+	DataValue Color
+	// Err is the error color.
+	Err Color
+	// Hostname is the hostname color.
+	Hostname Color
+	// Stack is the stack trace color.
+	Stack Color
+	// Timestamp is the timestamp color.
+	Timestamp Color
+}
+
+// DarkTheme returns a theme for dark terminal backgrounds, the default
+// colors.
+func DarkTheme() *Theme {
+	return &Theme{
+		Levels: map[stdLogger.Level]Color{
+			TraceLevel: ANSI256(240),
+			DebugLevel: ANSI256(245),
+			WarnLevel:  ANSI256(226),
+			ErrorLevel: ANSI256(166),
+			FatalLevel: ANSI256(124),
+			PanicLevel: ANSI256(196),
+		},
+		Default:   ANSI256(46),
+		Caller:    ANSI256(244),
+		DataLabel: ANSI256(111),
+		DataValue: ANSI256(180),
+		Err:       ANSI256(166),
+		Hostname:  ANSI256(39),
+		Stack:     ANSI256(244),
+		Timestamp: ANSI256(72),
+	}
+}
+
+// LightTheme returns a theme for light terminal backgrounds.
+func LightTheme() *Theme {
+	return &Theme{
+		Levels: map[stdLogger.Level]Color{
+			TraceLevel: ANSI256(246),
+			DebugLevel: ANSI256(243),
+			WarnLevel:  ANSI256(130),
+			ErrorLevel: ANSI256(160),
+			FatalLevel: ANSI256(88),
+			PanicLevel: ANSI256(124),
+		},
+		Default:   ANSI256(28),
+		Caller:    ANSI256(242),
+		DataLabel: ANSI256(25),
+		DataValue: ANSI256(94),
+		Err:       ANSI256(160),
+		Hostname:  ANSI256(31),
+		Stack:     ANSI256(242),
+		Timestamp: ANSI256(30),
+	}
+}
+
+// HighContrastTheme returns a theme of bright ANSI colors for dark terminal
+// backgrounds. It is shown the same way on 16 color terminals.
+func HighContrastTheme() *Theme {
+	return &Theme{
+		Levels: map[stdLogger.Level]Color{
+			TraceLevel: ANSI256(7),
+			DebugLevel: ANSI256(15),
+			WarnLevel:  ANSI256(11),
+			ErrorLevel: ANSI256(9),
+			FatalLevel: ANSI256(13),
+			PanicLevel: ANSI256(9),
+		},
+		Default:   ANSI256(10),
+		Caller:    ANSI256(14),
+		DataLabel: ANSI256(12),
+		DataValue: ANSI256(15),
+		Err:       ANSI256(9),
+		Hostname:  ANSI256(14),
+		Stack:     ANSI256(7),
+		Timestamp: ANSI256(11),
+	}
+}
+
+// levelColor returns the color of a level.
+func (t *Theme) levelColor(level stdLogger.Level) Color {
+	if c, ok := t.Levels[level]; ok && !c.IsZero() {
+		return c
+	}
+	if info, ok := LookupLevel(level); ok && "" != info.Color {
+		return parseColor(info.Color)
+	}
+	return t.Default
+}
+
+// getColors returns the TTY colors of an entry at a level. Without a theme
+// the color variables are used.
+func getColors(theme *Theme, profile ColorProfile, level stdLogger.Level) colors {
+	if ColorsNone == profile {
+		return colors{}
+	}
+	if nil == theme {
+		if Colors16 != profile {
+			return legacyColors(level)
+		}
+		return colors{
+			Caller:    parseColor(CallerColor).Sequence(profile),
+			DataLabel: parseColor(DataLabelColor).Sequence(profile),
+			DataValue: parseColor(DataValueColor).Sequence(profile),
+			Err:       parseColor(ERRORColor).Sequence(profile),
+			Hostname:  parseColor(HostnameColor).Sequence(profile),
+			Level:     parseColor(getLevelColor(level)).Sequence(profile),
+			Reset:     ResetColor,
+			Timestamp: parseColor(TimestampColor).Sequence(profile),
+			Trace:     parseColor(TraceColor).Sequence(profile),
+		}
+	}
+	return colors{
+		Caller:    theme.Caller.Sequence(profile),
+		DataLabel: theme.DataLabel.Sequence(profile),
+		DataValue: theme.DataValue.Sequence(profile),
+		Err:       theme.Err.Sequence(profile),
+		Hostname:  theme.Hostname.Sequence(profile),
+		Level:     theme.levelColor(level).Sequence(profile),
+		Reset:     ResetColor,
+		Timestamp: theme.Timestamp.Sequence(profile),
+		Trace:     theme.Stack.Sequence(profile),
+	}
+}
+
+// legacyColors returns the colors set by the color variables.
+func legacyColors(level stdLogger.Level) colors {
+	return colors{
+		Caller:    CallerColor,
+		DataLabel: DataLabelColor,
+		DataValue: DataValueColor,
+		Err:       ERRORColor,
+		Hostname:  HostnameColor,
+		Level:     getLevelColor(level),
+		Reset:     ResetColor,
+		Timestamp: TimestampColor,
+		Trace:     TraceColor,
+	}
+}
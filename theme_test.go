@@ -0,0 +1,160 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setenv sets environment variables and returns a function restoring them.
+func setenv(vars map[string]string) func() {
+	saved := map[string]*string{}
+	for k, v := range vars {
+		if old, ok := os.LookupEnv(k); ok {
+			saved[k] = &old
+		} else {
+			saved[k] = nil
+		}
+		if "" == v {
+			os.Unsetenv(k)
+		} else {
+			os.Setenv(k, v)
+		}
+	}
+	return func() {
+		for k, v := range saved {
+			if nil == v {
+				os.Unsetenv(k)
+			} else {
+				os.Setenv(k, *v)
+			}
+		}
+	}
+}
+
+func TestDetectColorProfile(t *testing.T) {
+	testCases := []struct {
+		env     map[string]string
+		profile ColorProfile
+	}{
+		{map[string]string{"TERM": ""}, Colors256},
+		{map[string]string{"TERM": "dumb"}, ColorsNone},
+		{map[string]string{"TERM": "xterm"}, Colors16},
+		{map[string]string{"TERM": "xterm-256color"}, Colors256},
+		{map[string]string{"TERM": "xterm-direct"}, ColorsTrue},
+		{map[string]string{"TERM": "xterm", "COLORTERM": "truecolor"}, ColorsTrue},
+		{map[string]string{"TERM": "xterm-256color", "COLORTERM": "24bit"}, ColorsTrue},
+		{map[string]string{"TERM": "xterm-256color", "COLORTERM": "truecolor", "NO_COLOR": "1"}, ColorsNone},
+	}
+	for _, tc := range testCases {
+		env := map[string]string{"TERM": "", "COLORTERM": "", "NO_COLOR": ""}
+		for k, v := range tc.env {
+			env[k] = v
+		}
+		restore := setenv(env)
+		assert.Equal(t, tc.profile, DetectColorProfile(), tc.env)
+		restore()
+	}
+}
+
+func TestColorSequence(t *testing.T) {
+	assert.True(t, Color{}.IsZero())
+	assert.Equal(t, "", Color{}.Sequence(ColorsTrue))
+	assert.Equal(t, "", RGB(255, 0, 0).Sequence(ColorsNone))
+
+	assert.Equal(t, "\033[38;2;255;135;0m", Hex(0xff8700).Sequence(ColorsTrue))
+	assert.Equal(t, "\033[38;5;208m", Hex(0xff8700).Sequence(Colors256))
+	assert.Equal(t, "\033[38;5;208m", Hex(0xff8700).Sequence(ColorsAuto))
+	assert.Equal(t, "\033[38;5;244m", RGB(128, 128, 128).Sequence(Colors256))
+	assert.Equal(t, "\033[38;5;16m", RGB(0, 0, 0).Sequence(Colors256))
+	assert.Equal(t, "\033[38;5;231m", RGB(255, 255, 255).Sequence(Colors256))
+	assert.Equal(t, "\033[91m", RGB(250, 10, 10).Sequence(Colors16))
+	assert.Equal(t, "\033[30m", RGB(0, 0, 0).Sequence(Colors16))
+
+	assert.Equal(t, "\033[38;5;46m", ANSI256(46).Sequence(ColorsTrue))
+	assert.Equal(t, "\033[38;5;46m", ANSI256(46).Sequence(Colors256))
+	assert.Equal(t, "\033[92m", ANSI256(46).Sequence(Colors16))
+	assert.Equal(t, "\033[33m", ANSI256(3).Sequence(Colors16))
+	assert.Equal(t, "\033[90m", ANSI256(244).Sequence(Colors16))
+
+	assert.Equal(t, "\033[1;31m", Escape("\033[1;31m").Sequence(Colors16))
+	assert.Equal(t, "", Escape("\033[1;31m").Sequence(ColorsNone))
+
+	assert.Equal(t, ANSI256(46), parseColor(DEFAULTColor))
+	assert.Equal(t, Escape("\033[1m"), parseColor("\033[1m"))
+	assert.True(t, parseColor("").IsZero())
+}
+
+func TestThemes(t *testing.T) {
+	for _, theme := range []*Theme{DarkTheme(), LightTheme(), HighContrastTheme()} {
+		for _, level := range AllLevels {
+			assert.False(t, theme.levelColor(level).IsZero(), level)
+		}
+	}
+
+	// The dark theme matches the color variables.
+	for _, level := range AllLevels {
+		assert.Equal(t, legacyColors(level), getColors(DarkTheme(), Colors256, level), level)
+		assert.Equal(t, getColors(nil, Colors16, level), getColors(DarkTheme(), Colors16, level), level)
+	}
+	assert.Equal(t, colors{}, getColors(LightTheme(), ColorsNone, InfoLevel))
+
+	// Registered colors are used for levels the theme doesn't have.
+	defer registerNotice(t)()
+	assert.Equal(t, "\033[38;5;45m", getColors(LightTheme(), Colors256, testNoticeLevel).Level)
+	theme := LightTheme()
+	theme.Levels[testNoticeLevel] = Hex(0x0000ff)
+	assert.Equal(t, "\033[38;2;0;0;255m", getColors(theme, ColorsTrue, testNoticeLevel).Level)
+}
+
+func TestFormatterThemes(t *testing.T) {
+	defer setenv(map[string]string{"NO_COLOR": "", "CLICOLOR_FORCE": ""})()
+
+	logLine := func(formatter Formatter) string {
+		var buffer bytes.Buffer
+		logger := New()
+		logger.Out = &buffer
+		logger.Formatter = formatter
+		logger.WithField("animal", "walrus").Warn("attack")
+		return buffer.String()
+	}
+
+	dark := &TextFormatter{ForceTTY: true, ColorProfile: Colors256, Theme: DarkTheme()}
+	light := &TextFormatter{ForceTTY: true, ColorProfile: ColorsTrue, Theme: &Theme{
+		Levels:    LightTheme().Levels,
+		DataLabel: Hex(0x123456),
+	}}
+	assert.True(t, strings.HasPrefix(logLine(dark), WARNColor+" warn"), logLine(dark))
+	assert.True(t, strings.HasPrefix(logLine(light), "\033[38;5;130m warn"), logLine(light))
+	assert.Contains(t, logLine(light), "\033[38;2;18;52;86manimal")
+
+	json := logLine(&JSONFormatter{ForceTTY: true, ColorProfile: Colors16, Theme: HighContrastTheme()})
+	assert.Contains(t, json, "\033[93m warn")
+	assert.Contains(t, json, "\033[94manimal")
+
+	plain := logLine(&TextFormatter{ForceTTY: true, ColorProfile: ColorsNone})
+	assert.NotContains(t, plain, "\033[")
+	assert.True(t, strings.HasPrefix(plain, " warn"), plain)
+	assert.Contains(t, plain, "⇢")
+
+	restore := setenv(map[string]string{"NO_COLOR": "1"})
+	plain = logLine(&JSONFormatter{ForceTTY: true})
+	restore()
+	assert.NotContains(t, plain, "\033[")
+	assert.Contains(t, plain, "{\n")
+
+	restore = setenv(map[string]string{"CLICOLOR_FORCE": "1", "TERM": "xterm-256color", "COLORTERM": ""})
+	forced := logLine(&TextFormatter{})
+	disabled := logLine(&TextFormatter{DisableTTY: true})
+	restore()
+	assert.True(t, strings.HasPrefix(forced, WARNColor), forced)
+	assert.NotContains(t, disabled, "\033[")
+
+	restore = setenv(map[string]string{"CLICOLOR_FORCE": "0"})
+	unforced := logLine(&TextFormatter{})
+	restore()
+	assert.NotContains(t, unforced, "\033[")
+}
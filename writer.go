@@ -30,6 +30,8 @@ func (entry *Entry) WriterLevel(level stdLogger.Level) *io.PipeWriter {
 	var printFunc func(args ...interface{})
 
 	switch level {
+	case TraceLevel:
+		printFunc = entry.Trace
 	case DebugLevel:
 		printFunc = entry.Debug
 	case InfoLevel:
@@ -43,7 +45,13 @@ func (entry *Entry) WriterLevel(level stdLogger.Level) *io.PipeWriter {
 	case PanicLevel:
 		printFunc = entry.Panic
 	default:
-		printFunc = entry.Print
+		if _, ok := LookupLevel(level); ok {
+			printFunc = func(args ...interface{}) {
+				entry.Log(level, args...)
+			}
+		} else {
+			printFunc = entry.Print
+		}
 	}
 
 	go entry.writerScanner(reader, printFunc)